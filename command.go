@@ -0,0 +1,173 @@
+package main
+
+import (
+	"fmt"
+	"github.com/sandertv/gophertunnel/minecraft/protocol"
+	"github.com/sandertv/gophertunnel/minecraft/protocol/packet"
+	"math"
+	"slices"
+	"strings"
+)
+
+// defaultCommandPrefix is the prefix used for commands handled by Tedac if no other prefix is configured.
+const defaultCommandPrefix = "/tedac"
+
+// Command is a command handled by Tedac itself rather than by the remote server. It is run by players using the
+// command prefix of Tedac followed by the name of the command, such as `/tedac ping`.
+type Command struct {
+	// Name is the name of the command, which follows the command prefix.
+	Name string
+	// Description is a short description of the command shown to players.
+	Description string
+	// Parameters are the parameters of the command. They are only used for autocompletion.
+	Parameters []protocol.CommandParameter
+	// Run is called when a player runs the command, with the arguments following the name of the command.
+	Run func(s *Session, args []string)
+}
+
+// RegisterCommand registers a command that players may run through Tedac. Existing commands with the same name are
+// replaced.
+func (t *Tedac) RegisterCommand(cmd Command) {
+	t.commandMu.Lock()
+	defer t.commandMu.Unlock()
+	t.commands[strings.ToLower(cmd.Name)] = cmd
+}
+
+// Commands returns all commands registered, sorted by name.
+func (t *Tedac) Commands() []Command {
+	t.commandMu.RLock()
+	defer t.commandMu.RUnlock()
+	commands := make([]Command, 0, len(t.commands))
+	for _, cmd := range t.commands {
+		commands = append(commands, cmd)
+	}
+	slices.SortFunc(commands, func(a, b Command) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return commands
+}
+
+// command looks up a registered command by its name.
+func (t *Tedac) command(name string) (Command, bool) {
+	t.commandMu.RLock()
+	defer t.commandMu.RUnlock()
+	cmd, ok := t.commands[strings.ToLower(name)]
+	return cmd, ok
+}
+
+// handleCommand runs the command in the line passed if it starts with the command prefix of Tedac. It returns true
+// if the line was meant for Tedac and should not be forwarded to the remote server.
+func (s *Session) handleCommand(line string) bool {
+	prefix := s.t.commandPrefix
+	if len(line) < len(prefix) || !strings.EqualFold(line[:len(prefix)], prefix) {
+		return false
+	}
+	rest := line[len(prefix):]
+	if rest != "" && rest[0] != ' ' {
+		// The line only starts with the prefix, such as /tedacs, so it is not meant for us.
+		return false
+	}
+	args := strings.Fields(rest)
+	if len(args) == 0 {
+		s.sendCommandUsage()
+		return true
+	}
+	cmd, ok := s.t.command(args[0])
+	if !ok {
+		s.Message(fmt.Sprintf("§cUnknown command %q. Use %v for a list of commands.", args[0], prefix))
+		return true
+	}
+	cmd.Run(s, args[1:])
+	return true
+}
+
+// sendCommandUsage sends a list of all commands registered to the client of the Session.
+func (s *Session) sendCommandUsage() {
+	var b strings.Builder
+	b.WriteString("§eTedac commands:")
+	for _, cmd := range s.t.Commands() {
+		b.WriteString(fmt.Sprintf("\n§7%v %v§r - %v", s.t.commandPrefix, cmd.Name, cmd.Description))
+	}
+	s.Message(b.String())
+}
+
+// addCommands adds the commands registered to the AvailableCommands packet passed, so that they autocomplete on the
+// client. Nothing is added if the command prefix is not a slash command.
+func (t *Tedac) addCommands(pk *packet.AvailableCommands) {
+	name, ok := strings.CutPrefix(t.commandPrefix, "/")
+	if !ok || name == "" || strings.Contains(name, " ") {
+		return
+	}
+	commands := t.Commands()
+	overloads := make([]protocol.CommandOverload, 0, len(commands))
+	for _, cmd := range commands {
+		// Every command is added as an enum with a single value, like the client expects for sub commands.
+		pk.EnumValues = append(pk.EnumValues, cmd.Name)
+		pk.Enums = append(pk.Enums, protocol.CommandEnum{
+			Type:         "Tedac" + cmd.Name,
+			ValueIndices: []uint{uint(len(pk.EnumValues) - 1)},
+		})
+		overloads = append(overloads, protocol.CommandOverload{
+			Parameters: append([]protocol.CommandParameter{{
+				Name:    cmd.Name,
+				Type:    protocol.CommandArgValid | protocol.CommandArgEnum | uint32(len(pk.Enums)-1),
+				Options: protocol.ParamOptionCollapseEnum,
+			}}, cmd.Parameters...),
+		})
+	}
+	pk.Commands = append(pk.Commands, protocol.Command{
+		Name:          name,
+		Description:   "Commands handled by Tedac.",
+		AliasesOffset: math.MaxUint32,
+		Overloads:     overloads,
+	})
+}
+
+// registerDefaultCommands registers the commands that Tedac provides by default.
+func (t *Tedac) registerDefaultCommands() {
+	t.RegisterCommand(Command{
+		Name:        "server",
+		Description: "Switch to another server.",
+		Parameters: []protocol.CommandParameter{{
+			Name: "name",
+			Type: protocol.CommandArgValid | protocol.CommandArgTypeString,
+		}},
+		Run: func(s *Session, args []string) {
+			if len(args) == 0 {
+				s.Message("§eAvailable servers: " + strings.Join(s.t.ServerNames(), ", "))
+				return
+			}
+			address, ok := s.t.ServerAddress(args[0])
+			if !ok {
+				s.Message(fmt.Sprintf("§cUnknown server %q.", args[0]))
+				return
+			}
+			s.Message("§eConnecting to " + args[0] + "...")
+			// Transferring takes a while, during which the packets of the client must still be read.
+			go func() {
+				if err := s.Transfer(address); err != nil {
+					s.t.log.Error("error while transferring using command: "+err.Error(), "player", s.Name(), "server", args[0])
+					s.Message("§cFailed to connect to " + args[0] + ".")
+				}
+			}()
+		},
+	})
+	t.RegisterCommand(Command{
+		Name:        "ping",
+		Description: "Show your latency to Tedac and to the server.",
+		Run: func(s *Session, _ []string) {
+			s.Message(fmt.Sprintf("§eLatency: §7%vms to Tedac, %vms from Tedac to the server.", s.conn.Latency().Milliseconds(), s.ServerConn().Latency().Milliseconds()))
+		},
+	})
+	t.RegisterCommand(Command{
+		Name:        "info",
+		Description: "Show information about your connection.",
+		Run: func(s *Session, _ []string) {
+			version := protocol.CurrentVersion
+			if s.Legacy() {
+				version = s.conn.Protocol().Ver()
+			}
+			s.Message(fmt.Sprintf("§eTedac§r\n§7Version: %v\nServer: %v\nPlayers: %v", version, s.ServerConn().RemoteAddr(), len(s.t.Sessions())))
+		},
+	})
+}
@@ -17,16 +17,20 @@ func main() {
 	goph := gophig.NewGophig[ProxyInfo]("./config.toml", gophig.TOMLMarshaler{}, os.ModePerm)
 	conf, err := goph.LoadConf()
 	if os.IsNotExist(err) {
-		_ = goph.SaveConf(ProxyInfo{
+		conf = ProxyInfo{
 			LocalAddress:  "127.0.0.1:19133",
 			RemoteAddress: "127.0.0.1:19132",
-		})
+			Servers:       map[string]string{"lobby": "127.0.0.1:19132"},
+			Routing:       Routing{Policy: RoutingPolicyDefault},
+			CommandPrefix: defaultCommandPrefix,
+		}
+		_ = goph.SaveConf(conf)
 	} else if err != nil {
 		log.Error("failed to initialise config: " + err.Error())
 		return
 	}
 
-	t := NewTedac(conf.LocalAddress)
+	t := NewTedac(conf)
 
 	log.Info("starting tedac...")
 	err = t.Connect(conf.RemoteAddress)
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"encoding/json"
+	"github.com/sandertv/gophertunnel/minecraft"
+	"github.com/sandertv/gophertunnel/minecraft/protocol/packet"
+	"math"
+	"net"
+	"slices"
+	"strings"
+)
+
+const (
+	// RoutingPolicyDefault connects every player to the default remote server.
+	RoutingPolicyDefault = "default"
+	// RoutingPolicyHostname connects players to the server configured for the hostname they joined with, falling
+	// back to the default remote server.
+	RoutingPolicyHostname = "hostname"
+	// RoutingPolicyLobby connects players to the default remote server and shows them a form listing all servers
+	// they can switch to.
+	RoutingPolicyLobby = "lobby"
+)
+
+// serverSelectorFormID is the form ID used for the server selector form. It is chosen to be high enough not to
+// collide with forms sent by remote servers.
+const serverSelectorFormID = math.MaxUint32 - 1
+
+// Routing holds the routing policy used to decide which server a player is connected to.
+type Routing struct {
+	// Policy is the routing policy used. It is one of the RoutingPolicy constants above. If left empty,
+	// RoutingPolicyDefault is used.
+	Policy string
+	// Hostnames maps hostnames players may join with to the names of the servers they should be connected to.
+	// It is only used for RoutingPolicyHostname.
+	Hostnames map[string]string
+}
+
+// ServerAddress returns the address of the server with the name passed. If no server with that name exists, false
+// is returned.
+func (t *Tedac) ServerAddress(name string) (string, bool) {
+	for n, address := range t.servers {
+		if strings.EqualFold(n, name) {
+			return address, true
+		}
+	}
+	return "", false
+}
+
+// ServerNames returns the sorted names of all servers configured.
+func (t *Tedac) ServerNames() []string {
+	names := make([]string, 0, len(t.servers))
+	for name := range t.servers {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// route returns the address of the remote server a newly connected player should be connected to. True is returned
+// if the player is reconnecting after being transferred to that server.
+func (t *Tedac) route(conn *minecraft.Conn) (string, bool) {
+	if address, ok := t.transfers.take(conn.IdentityData().Identity); ok {
+		return address, true
+	}
+	if t.routing.Policy == RoutingPolicyHostname {
+		host, _, err := net.SplitHostPort(conn.ClientData().ServerAddress)
+		if err != nil {
+			host = conn.ClientData().ServerAddress
+		}
+		for hostname, name := range t.routing.Hostnames {
+			if !strings.EqualFold(hostname, host) {
+				continue
+			}
+			if address, ok := t.ServerAddress(name); ok {
+				return address, false
+			}
+			t.log.Error("unknown server configured for hostname", "hostname", hostname, "server", name)
+		}
+	}
+	return t.remoteAddress, false
+}
+
+// routingStatusProvider is a minecraft.ServerStatusProvider that shows the MOTD of the default remote server along
+// with the players of all servers configured, so that the server list reflects every server players may be routed
+// to. Pings do not hold the hostname used, so the status cannot differ per hostname.
+type routingStatusProvider struct {
+	def     *minecraft.ForeignStatusProvider
+	servers []*minecraft.ForeignStatusProvider
+}
+
+// newRoutingStatusProvider creates a routingStatusProvider for the default remote server and the servers passed.
+// Servers with an invalid address are left out of the player count.
+func (t *Tedac) newRoutingStatusProvider(def *minecraft.ForeignStatusProvider) *routingStatusProvider {
+	p := &routingStatusProvider{def: def}
+	for _, name := range t.ServerNames() {
+		address := t.servers[name]
+		if address == t.remoteAddress {
+			continue
+		}
+		provider, err := minecraft.NewForeignStatusProvider(address)
+		if err != nil {
+			t.log.Error("error while querying server status: "+err.Error(), "server", name)
+			continue
+		}
+		p.servers = append(p.servers, provider)
+	}
+	return p
+}
+
+// ServerStatus ...
+func (p *routingStatusProvider) ServerStatus(playerCount, maxPlayers int) minecraft.ServerStatus {
+	status := p.def.ServerStatus(playerCount, maxPlayers)
+	for _, provider := range p.servers {
+		s := provider.ServerStatus(playerCount, maxPlayers)
+		status.PlayerCount += s.PlayerCount
+		status.MaxPlayers += s.MaxPlayers
+	}
+	return status
+}
+
+// Close stops querying the status of all servers.
+func (p *routingStatusProvider) Close() error {
+	_ = p.def.Close()
+	for _, provider := range p.servers {
+		_ = provider.Close()
+	}
+	return nil
+}
+
+// SendServerSelector sends a form to the client of the Session listing all servers configured, which the player
+// may use to switch to another server.
+func (s *Session) SendServerSelector() {
+	type button struct {
+		Text string `json:"text"`
+	}
+	names := s.t.ServerNames()
+	buttons := make([]button, 0, len(names))
+	for _, name := range names {
+		buttons = append(buttons, button{Text: name})
+	}
+	data, _ := json.Marshal(map[string]any{
+		"type":    "form",
+		"title":   "Server Selector",
+		"content": "Select the server you want to play on.",
+		"buttons": buttons,
+	})
+	_ = s.conn.WritePacket(&packet.ModalFormRequest{FormID: serverSelectorFormID, FormData: data})
+}
+
+// handleServerSelectorResponse handles the response of the client to the server selector form.
+func (s *Session) handleServerSelectorResponse(pk *packet.ModalFormResponse) {
+	data, ok := pk.ResponseData.Value()
+	if !ok {
+		return
+	}
+	var index int
+	if err := json.Unmarshal(data, &index); err != nil {
+		return
+	}
+	names := s.t.ServerNames()
+	if index < 0 || index >= len(names) {
+		return
+	}
+	address, _ := s.t.ServerAddress(names[index])
+	// Connecting to the server takes a while, during which the packets of the client should still be handled.
+	go func() {
+		if err := s.Transfer(address); err != nil {
+			s.t.log.Error("error while transferring to selected server: "+err.Error(), "player", s.Name(), "server", names[index])
+			s.Message("§cFailed to connect to " + names[index] + ".")
+		}
+	}()
+}
@@ -0,0 +1,756 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"github.com/df-mc/atomic"
+	"github.com/didntpot/tedac/tedac"
+	"github.com/didntpot/tedac/tedac/chunk"
+	"github.com/didntpot/tedac/tedac/legacyprotocol/legacypacket"
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/sandertv/gophertunnel/minecraft"
+	"github.com/sandertv/gophertunnel/minecraft/nbt"
+	"github.com/sandertv/gophertunnel/minecraft/protocol"
+	"github.com/sandertv/gophertunnel/minecraft/protocol/login"
+	"github.com/sandertv/gophertunnel/minecraft/protocol/packet"
+	"math"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Session is a single player proxied by Tedac. It owns the connection of the client, the connection to the remote
+// server and all state required to translate between the two.
+type Session struct {
+	t *Tedac
+
+	conn       *minecraft.Conn
+	clientData login.ClientData
+
+	// mu protects the connection to the remote server and the game data received from it, which change when the
+	// Session is transferred to another server.
+	mu         sync.RWMutex
+	serverConn *minecraft.Conn
+	data       minecraft.GameData
+
+	// rid and uid are the runtime and unique ID of the player on the current remote server. clientRID and
+	// clientUID are the IDs the client was spawned with, which never change, even after transferring.
+	rid, clientRID uint64
+	uid, clientUID int64
+
+	// shown holds everything the remote server showed to the client, so that it can be removed again when
+	// transferring to another server.
+	shown shownState
+
+	// dimension is the dimension the player is currently in on the remote server.
+	dimension atomic.Int32
+
+	// pendingDimensionChanges is the amount of dimension changes sent by Tedac itself that the client has not yet
+	// acknowledged. These acknowledgements are not forwarded to the remote server.
+	pendingDimensionChanges atomic.Int32
+
+	// oldMovementSystem specifies if the client does not send PlayerAuthInput packets itself, either because it is
+	// a v1.12.0 client or because the remote server uses client authoritative movement.
+	oldMovementSystem atomic.Bool
+
+	pos, lastPos *atomic.Value[mgl32.Vec3]
+	yaw, pitch   *atomic.Value[float32]
+
+	// onGround specifies if the client last reported being on the ground. teleported is set when the remote
+	// server teleported the player, so that the next PlayerAuthInput acknowledges it.
+	onGround, teleported *atomic.Value[bool]
+
+	// tick is the tick of the next PlayerAuthInput packet. It starts at the current tick of the remote server sent
+	// in the StartGame packet, and is set again when the Session is transferred, so that it stays in sync with it.
+	tick atomic.Uint64
+
+	startedSneaking, stoppedSneaking   *atomic.Value[bool]
+	startedSprinting, stoppedSprinting *atomic.Value[bool]
+	startedGliding, stoppedGliding     *atomic.Value[bool]
+	startedSwimming, stoppedSwimming   *atomic.Value[bool]
+	startedJumping                     *atomic.Value[bool]
+
+	// inputMu protects the block actions and item interactions of the client that are waiting to be sent in the
+	// next PlayerAuthInput packet.
+	inputMu          sync.Mutex
+	blockActions     []protocol.PlayerBlockAction
+	itemInteractions []protocol.UseItemTransactionData
+
+	// biomeBufferCache holds the biome data of LevelChunk packets that are waiting for their SubChunk response.
+	biomeBufferCache map[protocol.ChunkPos][]byte
+
+	once   sync.Once
+	closed chan struct{}
+}
+
+// newSession creates a new Session for the client and server connection passed. The server connection must have
+// been spawned already.
+func newSession(t *Tedac, conn, serverConn *minecraft.Conn, clientData login.ClientData) *Session {
+	data := serverConn.GameData()
+	s := &Session{
+		t:          t,
+		conn:       conn,
+		clientData: clientData,
+		serverConn: serverConn,
+		data:       data,
+
+		rid:       data.EntityRuntimeID,
+		clientRID: data.EntityRuntimeID,
+		uid:       data.EntityUniqueID,
+		clientUID: data.EntityUniqueID,
+
+		shown: newShownState(),
+
+		pos:     atomic.NewValue(data.PlayerPosition),
+		lastPos: atomic.NewValue(data.PlayerPosition),
+		yaw:     atomic.NewValue(data.Yaw),
+		pitch:   atomic.NewValue(data.Pitch),
+
+		onGround:   atomic.NewValue(false),
+		teleported: atomic.NewValue(false),
+
+		startedSneaking:  atomic.NewValue(false),
+		stoppedSneaking:  atomic.NewValue(false),
+		startedSprinting: atomic.NewValue(false),
+		stoppedSprinting: atomic.NewValue(false),
+		startedGliding:   atomic.NewValue(false),
+		stoppedGliding:   atomic.NewValue(false),
+		startedSwimming:  atomic.NewValue(false),
+		stoppedSwimming:  atomic.NewValue(false),
+		startedJumping:   atomic.NewValue(false),
+
+		biomeBufferCache: make(map[protocol.ChunkPos][]byte),
+		closed:           make(chan struct{}),
+	}
+	s.dimension.Store(data.Dimension)
+	s.tick.Store(uint64(data.Time))
+	s.oldMovementSystem.Store(s.usesOldMovementSystem(data))
+	return s
+}
+
+// usesOldMovementSystem checks if the client must be sent emulated PlayerAuthInput packets on the remote server with
+// the game data passed.
+func (s *Session) usesOldMovementSystem(data minecraft.GameData) bool {
+	return s.Legacy() || data.PlayerMovementSettings.MovementType == protocol.PlayerMovementModeClient
+}
+
+// Conn returns the connection of the client proxied by the Session.
+func (s *Session) Conn() *minecraft.Conn {
+	return s.conn
+}
+
+// ServerConn returns the connection the Session currently holds with the remote server.
+func (s *Session) ServerConn() *minecraft.Conn {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.serverConn
+}
+
+// GameData returns the game data sent by the remote server the Session is currently connected to.
+func (s *Session) GameData() minecraft.GameData {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.data
+}
+
+// Name returns the display name of the player proxied.
+func (s *Session) Name() string {
+	return s.conn.IdentityData().DisplayName
+}
+
+// XUID returns the XBOX Live user ID of the player proxied.
+func (s *Session) XUID() string {
+	return s.conn.IdentityData().XUID
+}
+
+// identity returns the identity UUID of the player proxied, which is unique to the player even if it is not logged
+// into XBOX Live.
+func (s *Session) identity() string {
+	return s.conn.IdentityData().Identity
+}
+
+// Legacy returns true if the client of the Session is connected using the v1.12.0 protocol.
+func (s *Session) Legacy() bool {
+	_, ok := s.conn.Protocol().(tedac.Protocol)
+	return ok
+}
+
+// Position returns the last known position of the player proxied.
+func (s *Session) Position() mgl32.Vec3 {
+	return s.pos.Load()
+}
+
+// Message sends a raw chat message to the client of the Session.
+func (s *Session) Message(message string) {
+	_ = s.conn.WritePacket(&packet.Text{TextType: packet.TextTypeRaw, Message: message})
+}
+
+// Kick disconnects the client of the Session with the message passed and closes the Session.
+func (s *Session) Kick(message string) {
+	_ = s.t.listener.Disconnect(s.conn, message)
+	s.Close()
+}
+
+// Close closes both connections of the Session and removes it from Tedac. Calling Close multiple times is a no-op.
+func (s *Session) Close() {
+	s.once.Do(func() {
+		close(s.closed)
+		_ = s.ServerConn().Close()
+		_ = s.conn.Close()
+		tedac.Release(s.conn)
+		s.t.removeSession(s)
+	})
+}
+
+// start starts the goroutines handling packets of the Session.
+func (s *Session) start() {
+	go s.tickMovement()
+	go s.handleClientPackets()
+	go s.handleServerPackets(s.serverConn)
+}
+
+// tickMovement emulates the PlayerAuthInput packets for clients that do not use the server authoritative movement
+// system. Like the client would, it sends one packet every tick, 20 times per second. Ticks are skipped while the
+// client sends PlayerAuthInput packets itself, which may change when the Session is transferred.
+func (s *Session) tickMovement() {
+	t := time.NewTicker(time.Second / 20)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-t.C:
+		case <-s.closed:
+			return
+		}
+		if !s.oldMovementSystem.Load() {
+			continue
+		}
+		currentPos, originalPos := s.pos.Load(), s.lastPos.Load()
+		s.lastPos.Store(currentPos)
+
+		currentYaw, currentPitch := s.yaw.Load(), s.pitch.Load()
+		// The client does not send its velocity, so the distance moved since the last tick is used as the delta
+		// instead. This is an approximation: it lags one tick behind and does not include the motion set by the
+		// server using SetActorMotion, but it is close enough for the movement checks of most servers.
+		velocity := currentPos.Sub(originalPos)
+		moveVector := moveVector(velocity, currentYaw)
+
+		inputs := protocol.NewBitset(packet.PlayerAuthInputBitsetSize)
+		setMoveInputs(inputs, moveVector)
+		if s.startedSneaking.CompareAndSwap(true, false) {
+			inputs.Set(packet.InputFlagStartSneaking)
+		}
+		if s.stoppedSneaking.CompareAndSwap(true, false) {
+			inputs.Set(packet.InputFlagStopSneaking)
+		}
+		if s.startedSprinting.CompareAndSwap(true, false) {
+			inputs.Set(packet.InputFlagStartSprinting)
+		}
+		if s.stoppedSprinting.CompareAndSwap(true, false) {
+			inputs.Set(packet.InputFlagStopSprinting)
+		}
+		if s.startedGliding.CompareAndSwap(true, false) {
+			inputs.Set(packet.InputFlagStartGliding)
+		}
+		if s.stoppedGliding.CompareAndSwap(true, false) {
+			inputs.Set(packet.InputFlagStopGliding)
+		}
+		if s.startedSwimming.CompareAndSwap(true, false) {
+			inputs.Set(packet.InputFlagStartSwimming)
+		}
+		if s.stoppedSwimming.CompareAndSwap(true, false) {
+			inputs.Set(packet.InputFlagStopSwimming)
+		}
+		if s.startedJumping.CompareAndSwap(true, false) {
+			inputs.Set(packet.InputFlagJumping)
+		}
+		if s.onGround.Load() {
+			inputs.Set(packet.InputFlagVerticalCollision)
+		}
+		if s.teleported.CompareAndSwap(true, false) {
+			inputs.Set(packet.InputFlagHandledTeleport)
+		}
+		blockActions, itemInteraction, interacted := s.takeInputActions()
+		if len(blockActions) > 0 {
+			inputs.Set(packet.InputFlagPerformBlockActions)
+		}
+		if interacted {
+			inputs.Set(packet.InputFlagPerformItemInteraction)
+		}
+
+		err := s.ServerConn().WritePacket(&packet.PlayerAuthInput{
+			Delta:               velocity,
+			MoveVector:          moveVector,
+			RawMoveVector:       moveVector,
+			AnalogueMoveVector:  moveVector,
+			CameraOrientation:   cameraOrientation(currentYaw, currentPitch),
+			HeadYaw:             currentYaw,
+			InputData:           inputs,
+			InputMode:           packet.InputModeMouse,
+			InteractionModel:    packet.InteractionModelCrosshair,
+			Pitch:               currentPitch,
+			PlayMode:            packet.PlayModeNormal,
+			Position:            currentPos,
+			Tick:                s.tick.Add(1) - 1,
+			Yaw:                 currentYaw,
+			ItemInteractionData: itemInteraction,
+			BlockActions:        blockActions,
+		})
+		if err != nil {
+			return
+		}
+		_ = s.conn.WritePacket(&packet.NetworkChunkPublisherUpdate{ // cry about it
+			Position: protocol.BlockPos{int32(currentPos.X()), int32(currentPos.Y()), int32(currentPos.Z())},
+			Radius:   uint32(s.GameData().ChunkRadius) << 4,
+		})
+	}
+}
+
+// moveVector derives the movement input of the client from the distance it moved in a tick and the yaw it is
+// facing. The X value is positive when moving to the left, and the Y value is positive when moving forward. The
+// resulting vector is normalised, like it would be for keyboard input.
+func moveVector(delta mgl32.Vec3, yaw float32) mgl32.Vec2 {
+	const epsilon = 0.001
+
+	sin, cos := math.Sincos(float64(mgl32.DegToRad(yaw)))
+	forward := float64(delta.Z())*cos - float64(delta.X())*sin
+	left := float64(delta.X())*cos + float64(delta.Z())*sin
+
+	var vec mgl32.Vec2
+	if math.Abs(left) > epsilon {
+		vec[0] = float32(math.Copysign(1, left))
+	}
+	if math.Abs(forward) > epsilon {
+		vec[1] = float32(math.Copysign(1, forward))
+	}
+	if vec.Len() > 1 {
+		vec = vec.Normalize()
+	}
+	return vec
+}
+
+// setMoveInputs sets the directional input flags matching the move vector passed.
+func setMoveInputs(inputs protocol.Bitset, moveVector mgl32.Vec2) {
+	left, right := moveVector.X() > 0, moveVector.X() < 0
+	up, down := moveVector.Y() > 0, moveVector.Y() < 0
+	switch {
+	case up && left:
+		inputs.Set(packet.InputFlagUpLeft)
+	case up && right:
+		inputs.Set(packet.InputFlagUpRight)
+	case down && left:
+		inputs.Set(packet.InputFlagDownLeft)
+	case down && right:
+		inputs.Set(packet.InputFlagDownRight)
+	}
+	if up {
+		inputs.Set(packet.InputFlagUp)
+	}
+	if down {
+		inputs.Set(packet.InputFlagDown)
+	}
+	if left {
+		inputs.Set(packet.InputFlagLeft)
+	}
+	if right {
+		inputs.Set(packet.InputFlagRight)
+	}
+}
+
+// cameraOrientation returns the direction the camera of a player with the yaw and pitch passed is facing.
+func cameraOrientation(yaw, pitch float32) mgl32.Vec3 {
+	yawRad, pitchRad := float64(mgl32.DegToRad(yaw)), float64(mgl32.DegToRad(pitch))
+	m := math.Cos(pitchRad)
+	return mgl32.Vec3{
+		float32(-m * math.Sin(yawRad)),
+		float32(-math.Sin(pitchRad)),
+		float32(m * math.Cos(yawRad)),
+	}
+}
+
+// takeInputActions returns the block actions of the client that should be sent in the next PlayerAuthInput packet,
+// along with the first item interaction waiting to be sent, if any. Only one item interaction can be sent per
+// PlayerAuthInput, so the others remain queued.
+func (s *Session) takeInputActions() ([]protocol.PlayerBlockAction, protocol.UseItemTransactionData, bool) {
+	s.inputMu.Lock()
+	defer s.inputMu.Unlock()
+
+	blockActions := s.blockActions
+	s.blockActions = nil
+	if len(s.itemInteractions) == 0 {
+		return blockActions, protocol.UseItemTransactionData{}, false
+	}
+	itemInteraction := s.itemInteractions[0]
+	s.itemInteractions = s.itemInteractions[1:]
+	return blockActions, itemInteraction, true
+}
+
+// handleClientPackets reads packets from the client and forwards them to the remote server.
+func (s *Session) handleClientPackets() {
+	defer s.Close()
+	for {
+		pk, err := s.conn.ReadPacket()
+		if err != nil {
+			_ = s.t.listener.Disconnect(s.conn, "connection lost")
+			return
+		}
+		if s.handleClientPacket(pk) {
+			continue
+		}
+		s.translateEntityIDs(pk)
+		if err := s.ServerConn().WritePacket(pk); err != nil {
+			message := "connection lost"
+			var disconnect minecraft.DisconnectError
+			if errors.As(errors.Unwrap(err), &disconnect) {
+				message = disconnect.Error()
+			}
+			_ = s.t.listener.Disconnect(s.conn, message)
+			return
+		}
+	}
+}
+
+// handleClientPacket handles a packet sent by the client. It returns true if the packet was handled by the Session
+// and should not be forwarded to the remote server.
+func (s *Session) handleClientPacket(pk packet.Packet) bool {
+	switch pk := pk.(type) {
+	case *packet.MovePlayer:
+		if !s.oldMovementSystem.Load() {
+			break
+		}
+		s.pos.Store(pk.Position)
+		s.yaw.Store(pk.Yaw)
+		s.pitch.Store(pk.Pitch)
+		s.onGround.Store(pk.OnGround)
+		return true
+	case *packet.CommandRequest:
+		return s.handleCommand(pk.CommandLine)
+	case *packet.Text:
+		if pk.TextType == packet.TextTypeChat {
+			return s.handleCommand(pk.Message)
+		}
+	case *packet.ModalFormResponse:
+		if pk.FormID == serverSelectorFormID {
+			s.handleServerSelectorResponse(pk)
+			return true
+		}
+	case *packet.InventoryTransaction:
+		data, ok := pk.TransactionData.(*protocol.UseItemTransactionData)
+		if !ok || !s.oldMovementSystem.Load() {
+			break
+		}
+		// Item interactions are sent in the PlayerAuthInput packet by clients using the new movement system.
+		interaction := *data
+		interaction.Actions = pk.Actions
+		interaction.TriggerType = protocol.TriggerTypePlayerInput
+		interaction.ClientPrediction = protocol.ClientPredictionSuccess
+
+		s.inputMu.Lock()
+		s.itemInteractions = append(s.itemInteractions, interaction)
+		s.inputMu.Unlock()
+		return true
+	case *packet.PlayerAction:
+		if pk.ActionType == protocol.PlayerActionDimensionChangeDone && s.pendingDimensionChanges.Load() > 0 {
+			s.pendingDimensionChanges.Add(-1)
+			return true
+		}
+		if !s.oldMovementSystem.Load() {
+			break
+		}
+		switch pk.ActionType {
+		case legacypacket.PlayerActionStartBreak, legacypacket.PlayerActionAbortBreak, legacypacket.PlayerActionStopBreak, legacypacket.PlayerActionContinueBreak:
+			action := protocol.PlayerBlockAction{
+				Action:   pk.ActionType,
+				BlockPos: pk.BlockPosition,
+				Face:     pk.BlockFace,
+			}
+			if pk.ActionType == legacypacket.PlayerActionContinueBreak {
+				action.Action = protocol.PlayerActionCrackBreak
+			}
+
+			s.inputMu.Lock()
+			s.blockActions = append(s.blockActions, action)
+			s.inputMu.Unlock()
+			return true
+		case legacypacket.PlayerActionJump:
+			s.startedJumping.Store(true)
+			return true
+		case legacypacket.PlayerActionStartSprint:
+			s.startedSprinting.Store(true)
+			return true
+		case legacypacket.PlayerActionStopSprint:
+			s.stoppedSprinting.Store(true)
+			return true
+		case legacypacket.PlayerActionStartSneak:
+			s.startedSneaking.Store(true)
+			return true
+		case legacypacket.PlayerActionStopSneak:
+			s.stoppedSneaking.Store(true)
+			return true
+		case legacypacket.PlayerActionStartSwimming:
+			s.startedSwimming.Store(true)
+			return true
+		case legacypacket.PlayerActionStopSwimming:
+			s.stoppedSwimming.Store(true)
+			return true
+		case legacypacket.PlayerActionStartGlide:
+			s.startedGliding.Store(true)
+			return true
+		case legacypacket.PlayerActionStopGlide:
+			s.stoppedGliding.Store(true)
+			return true
+		}
+	}
+	return false
+}
+
+// handleServerPackets reads packets from the remote server connection passed and forwards them to the client. It
+// returns without closing the Session if the Session was transferred to another server in the meantime.
+func (s *Session) handleServerPackets(serverConn *minecraft.Conn) {
+	for {
+		pk, err := serverConn.ReadPacket()
+		if s.ServerConn() != serverConn {
+			// The Session was transferred to another server, so this connection is no longer in use and any
+			// packet still read from it belongs to the previous server.
+			return
+		}
+		if err != nil {
+			var disconnect minecraft.DisconnectError
+			if errors.As(errors.Unwrap(err), &disconnect) {
+				_ = s.t.listener.Disconnect(s.conn, disconnect.Error())
+			} else {
+				_ = s.t.listener.Disconnect(s.conn, "connection lost")
+			}
+			s.Close()
+			return
+		}
+		s.translateEntityIDs(pk)
+		if s.handleServerPacket(pk) {
+			continue
+		}
+		if err := s.conn.WritePacket(pk); err != nil {
+			s.Close()
+			return
+		}
+	}
+}
+
+// handleServerPacket handles a packet sent by the remote server. It returns true if the packet was handled by the
+// Session and should not be forwarded to the client.
+func (s *Session) handleServerPacket(pk packet.Packet) bool {
+	switch pk := pk.(type) {
+	case *packet.MovePlayer:
+		if s.oldMovementSystem.Load() && pk.EntityRuntimeID == s.clientRID {
+			s.teleport(pk.Position, pk.Yaw, pk.Pitch, pk.OnGround)
+		}
+	case *packet.MoveActorAbsolute:
+		if s.oldMovementSystem.Load() && pk.EntityRuntimeID == s.clientRID {
+			s.teleport(pk.Position, pk.Rotation[2], pk.Rotation[0], pk.Flags&packet.MoveFlagOnGround != 0)
+		}
+	case *packet.CorrectPlayerMovePrediction:
+		if !s.oldMovementSystem.Load() || pk.PredictionType != packet.PredictionTypePlayer {
+			break
+		}
+		// Clients using the old movement system have no idea what to do with this packet, so we teleport them to
+		// the position the server expects them at instead.
+		yaw, pitch := s.yaw.Load(), s.pitch.Load()
+		s.teleport(pk.Position, yaw, pitch, pk.OnGround)
+		_ = s.conn.WritePacket(&packet.MovePlayer{
+			EntityRuntimeID: s.clientRID,
+			Position:        pk.Position,
+			Pitch:           pitch,
+			Yaw:             yaw,
+			HeadYaw:         yaw,
+			Mode:            packet.MoveModeReset,
+			OnGround:        pk.OnGround,
+		})
+		return true
+	case *packet.MoveActorDelta:
+		if s.oldMovementSystem.Load() && pk.EntityRuntimeID == s.clientRID {
+			s.pos.Store(pk.Position)
+			s.yaw.Store(pk.Rotation[2])
+			s.pitch.Store(pk.Rotation[0])
+		}
+	case *packet.ChangeDimension:
+		s.dimension.Store(pk.Dimension)
+	case *packet.AvailableCommands:
+		s.t.addCommands(pk)
+	case *packet.AddActor:
+		s.trackEntity(pk.EntityUniqueID)
+	case *packet.AddPlayer:
+		s.trackEntity(pk.AbilityData.EntityUniqueID)
+	case *packet.AddItemActor:
+		s.trackEntity(pk.EntityUniqueID)
+	case *packet.AddPainting:
+		s.trackEntity(pk.EntityUniqueID)
+	case *packet.RemoveActor:
+		s.mu.Lock()
+		delete(s.shown.entities, pk.EntityUniqueID)
+		s.mu.Unlock()
+	case *packet.PlayerList:
+		s.mu.Lock()
+		for _, entry := range pk.Entries {
+			if pk.ActionType == packet.PlayerListActionAdd {
+				s.shown.players[entry.UUID] = struct{}{}
+			} else {
+				delete(s.shown.players, entry.UUID)
+			}
+		}
+		s.mu.Unlock()
+	case *packet.SetDisplayObjective:
+		s.mu.Lock()
+		s.shown.objectives[pk.ObjectiveName] = struct{}{}
+		s.mu.Unlock()
+	case *packet.RemoveObjective:
+		s.mu.Lock()
+		delete(s.shown.objectives, pk.ObjectiveName)
+		s.mu.Unlock()
+	case *packet.BossEvent:
+		s.mu.Lock()
+		if pk.EventType == packet.BossEventShow {
+			s.shown.bossBars[pk.BossEntityUniqueID] = struct{}{}
+		} else if pk.EventType == packet.BossEventHide {
+			delete(s.shown.bossBars, pk.BossEntityUniqueID)
+		}
+		s.mu.Unlock()
+	case *packet.MobEffect:
+		if pk.EntityRuntimeID != s.clientRID {
+			break
+		}
+		s.mu.Lock()
+		if pk.Operation == packet.MobEffectRemove {
+			delete(s.shown.effects, pk.EffectType)
+		} else {
+			s.shown.effects[pk.EffectType] = struct{}{}
+		}
+		s.mu.Unlock()
+	case *packet.SubChunk:
+		if !s.Legacy() {
+			// Only Tedac clients should receive the old format.
+			break
+		}
+		s.handleSubChunk(pk)
+		return true
+	case *packet.LevelChunk:
+		if pk.SubChunkCount != protocol.SubChunkRequestModeLimitless && pk.SubChunkCount != protocol.SubChunkRequestModeLimited {
+			// No changes to be made here.
+			break
+		}
+		if !s.Legacy() {
+			// Only Tedac clients should receive the old format.
+			break
+		}
+		s.requestSubChunks(pk)
+		return true
+	case *packet.Transfer:
+		address := net.JoinHostPort(pk.Address, strconv.Itoa(int(pk.Port)))
+		err := s.Transfer(address)
+		if err == nil {
+			return true
+		}
+		s.t.log.Error("error while transferring seamlessly: "+err.Error(), "player", s.Name(), "address", address)
+
+		// Only the player being transferred should connect to the new server, so we keep track of it per player
+		// and send the client back to Tedac.
+		s.t.transfers.add(s.identity(), address)
+
+		pk.Address = s.t.LocalAddress()
+		pk.Port = s.t.LocalPort()
+	}
+	return false
+}
+
+// teleport updates the position and rotation of the player after being moved by the remote server. The next
+// PlayerAuthInput acknowledges the teleport and does not report any movement for it.
+func (s *Session) teleport(pos mgl32.Vec3, yaw, pitch float32, onGround bool) {
+	s.pos.Store(pos)
+	s.lastPos.Store(pos)
+	s.yaw.Store(yaw)
+	s.pitch.Store(pitch)
+	s.onGround.Store(onGround)
+	s.teleported.Store(true)
+}
+
+// trackEntity keeps track of an entity spawned by the remote server.
+func (s *Session) trackEntity(uniqueID int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.shown.entities[uniqueID] = struct{}{}
+}
+
+// requestSubChunks requests the sub chunks of a LevelChunk sent using the sub chunk request system, so that they
+// can be merged back into a single LevelChunk for the client.
+func (s *Session) requestSubChunks(pk *packet.LevelChunk) {
+	r := tedac.DimensionRange(s.dimension.Load())
+	max := r.Height() >> 4
+	if pk.SubChunkCount == protocol.SubChunkRequestModeLimited {
+		max = int(pk.HighestSubChunk)
+	}
+
+	offsets := make([]protocol.SubChunkOffset, 0, max)
+	for i := 0; i < max; i++ {
+		offsets = append(offsets, protocol.SubChunkOffset{0, int8(i + (r[0] >> 4)), 0})
+	}
+
+	s.mu.Lock()
+	s.biomeBufferCache[pk.Position] = pk.RawPayload[:len(pk.RawPayload)-1]
+	s.mu.Unlock()
+	_ = s.ServerConn().WritePacket(&packet.SubChunkRequest{
+		Position: protocol.SubChunkPos{pk.Position.X(), 0, pk.Position.Z()},
+		Offsets:  offsets,
+	})
+}
+
+// handleSubChunk merges the entries of a SubChunk packet into a LevelChunk and sends it to the client.
+func (s *Session) handleSubChunk(pk *packet.SubChunk) {
+	r := tedac.DimensionRange(s.dimension.Load())
+	chunkBuf := bytes.NewBuffer(nil)
+	blockEntities := make([]map[string]any, 0)
+	for _, entry := range pk.SubChunkEntries {
+		if entry.Result != protocol.SubChunkResultSuccess {
+			chunkBuf.Write([]byte{
+				chunk.SubChunkVersion,
+				0, // The client will treat this as all air.
+				uint8(entry.Offset[1]),
+			})
+			continue
+		}
+
+		var ind uint8
+		readBuf := bytes.NewBuffer(entry.RawPayload)
+		sub, err := chunk.DecodeSubChunk(tedac.MappingsOf(s.conn).AirRuntimeID(), r, readBuf, &ind, chunk.NetworkEncoding)
+		if err != nil {
+			s.t.log.Error("error decoding sub chunk: " + err.Error())
+			continue
+		}
+
+		var blockEntity map[string]any
+		dec := nbt.NewDecoderWithEncoding(readBuf, nbt.NetworkLittleEndian)
+		for {
+			if err := dec.Decode(&blockEntity); err != nil {
+				break
+			}
+			blockEntities = append(blockEntities, blockEntity)
+		}
+
+		chunkBuf.Write(chunk.EncodeSubChunk(sub, chunk.NetworkEncoding, r, int(ind)))
+	}
+
+	chunkPos := protocol.ChunkPos{pk.Position.X(), pk.Position.Z()}
+	s.mu.Lock()
+	_, _ = chunkBuf.Write(append(s.biomeBufferCache[chunkPos], 0))
+	delete(s.biomeBufferCache, chunkPos)
+	s.mu.Unlock()
+
+	enc := nbt.NewEncoderWithEncoding(chunkBuf, nbt.NetworkLittleEndian)
+	for _, b := range blockEntities {
+		_ = enc.Encode(b)
+	}
+
+	_ = s.conn.WritePacket(&packet.LevelChunk{
+		Position:      chunkPos,
+		SubChunkCount: uint32(len(pk.SubChunkEntries)),
+		RawPayload:    append([]byte(nil), chunkBuf.Bytes()...),
+	})
+}
@@ -1,30 +1,22 @@
 package main
 
 import (
-	"bytes"
 	"context"
-	"encoding/base64"
 	"errors"
 	"fmt"
-	"github.com/df-mc/atomic"
-	"github.com/df-mc/dragonfly/server/world"
 	"github.com/didntpot/tedac/tedac"
-	"github.com/didntpot/tedac/tedac/chunk"
-	"github.com/didntpot/tedac/tedac/latestmappings"
-	"github.com/didntpot/tedac/tedac/legacyprotocol/legacypacket"
 	"github.com/google/uuid"
+	"github.com/samber/lo"
 	"github.com/sandertv/gophertunnel/minecraft"
-	"github.com/sandertv/gophertunnel/minecraft/nbt"
 	"github.com/sandertv/gophertunnel/minecraft/protocol"
-	"github.com/sandertv/gophertunnel/minecraft/protocol/packet"
 	"github.com/sandertv/gophertunnel/minecraft/resource"
 	"golang.org/x/oauth2"
 	"log/slog"
 	"net"
 	"os"
 	"strconv"
+	"strings"
 	"sync"
-	"time"
 )
 
 // Tedac ...
@@ -34,23 +26,81 @@ type Tedac struct {
 	localAddress  string
 	remoteAddress string
 
+	servers map[string]string
+	routing Routing
+	status  *routingStatusProvider
+
+	verticalOffset int32
+
+	customBlocks        map[string]string
+	customBlockFallback string
+
+	commandPrefix string
+	commandMu     sync.RWMutex
+	commands      map[string]Command
+
 	src oauth2.TokenSource
 	ctx context.Context
 
 	log *slog.Logger
 
+	// sessions holds all sessions currently proxied, keyed by the identity UUID of their player. Unlike the XUID,
+	// this is also set for players that are not logged into XBOX Live.
+	sessionMu sync.RWMutex
+	sessions  map[string]*Session
+
+	transfers *transfers
+
 	c chan interface{}
 }
 
 // NewTedac ...
-func NewTedac(localAddress string) *Tedac {
-	return &Tedac{localAddress: localAddress, src: tokenSource(), log: slog.Default(), c: make(chan interface{})}
+func NewTedac(conf ProxyInfo) *Tedac {
+	if conf.Routing.Policy == "" {
+		conf.Routing.Policy = RoutingPolicyDefault
+	}
+	if conf.CommandPrefix == "" {
+		conf.CommandPrefix = defaultCommandPrefix
+	}
+	t := &Tedac{
+		localAddress:        conf.LocalAddress,
+		servers:             conf.Servers,
+		routing:             conf.Routing,
+		verticalOffset:      conf.VerticalOffset,
+		customBlocks:        conf.CustomBlocks,
+		customBlockFallback: conf.CustomBlockFallback,
+		commandPrefix:       conf.CommandPrefix,
+		commands:            make(map[string]Command),
+		src:                 tokenSource(),
+		log:                 slog.Default(),
+		sessions:            make(map[string]*Session),
+		transfers:           newTransfers(),
+		c:                   make(chan interface{}),
+	}
+	t.registerDefaultCommands()
+	return t
 }
 
 // ProxyInfo ...
 type ProxyInfo struct {
 	LocalAddress  string
 	RemoteAddress string
+	// Servers maps the names of all backends players may be routed to to their addresses.
+	Servers map[string]string
+	// Routing is the routing policy used to decide which server players are connected to.
+	Routing Routing
+	// VerticalOffset is the Y value in the Overworld that v1.12.0 clients see as Y=0. Setting it to -64 allows
+	// these clients to see the terrain below Y=0, at the cost of not seeing anything above Y=191.
+	VerticalOffset int32
+	// CustomBlocks maps the names of custom blocks of the servers to the name of the vanilla block that v1.12.0
+	// clients are shown instead, such as minecraft:stone.
+	CustomBlocks map[string]string
+	// CustomBlockFallback is the name of the vanilla block that v1.12.0 clients are shown for custom blocks not
+	// present in CustomBlocks. If empty, these blocks are shown as update blocks.
+	CustomBlockFallback string
+	// CommandPrefix is the prefix of commands handled by Tedac itself, such as /tedac. Commands starting with a
+	// slash are also suggested to players.
+	CommandPrefix string
 }
 
 // ProxyingInfo ...
@@ -59,8 +109,14 @@ func (t *Tedac) ProxyingInfo() (ProxyInfo, error) {
 		return ProxyInfo{}, errors.New("no connection active")
 	}
 	return ProxyInfo{
-		LocalAddress:  t.localAddress,
-		RemoteAddress: t.remoteAddress,
+		LocalAddress:        t.localAddress,
+		RemoteAddress:       t.remoteAddress,
+		Servers:             t.servers,
+		Routing:             t.routing,
+		VerticalOffset:      t.verticalOffset,
+		CustomBlocks:        t.customBlocks,
+		CustomBlockFallback: t.customBlockFallback,
+		CommandPrefix:       t.commandPrefix,
 	}, nil
 }
 
@@ -84,6 +140,7 @@ func (t *Tedac) Terminate() {
 	}
 	t.c <- struct{}{}
 	_ = t.listener.Close()
+	_ = t.status.Close()
 }
 
 // Connect ...
@@ -138,6 +195,7 @@ func (t *Tedac) Connect(remoteAddress string) error {
 	}
 
 	t.remoteAddress = remoteAddress
+	t.status = t.newRoutingStatusProvider(p)
 
 	go t.startRPC()
 
@@ -145,7 +203,7 @@ func (t *Tedac) Connect(remoteAddress string) error {
 		AllowInvalidPackets: true,
 		AllowUnknownPackets: true,
 
-		StatusProvider: p,
+		StatusProvider: t.status,
 
 		ResourcePacks:     append(packs, cachedPacks...),
 		AcceptedProtocols: []minecraft.Protocol{tedac.Protocol{}},
@@ -165,11 +223,6 @@ func (t *Tedac) Connect(remoteAddress string) error {
 	return nil
 }
 
-var (
-	// airRID is the runtime ID of the air block in the latest version of the game.
-	airRID, _ = latestmappings.StateToRuntimeID("minecraft:air", nil)
-)
-
 // handleConn ...
 func (t *Tedac) handleConn(conn *minecraft.Conn) {
 	clientData := conn.ClientData()
@@ -178,28 +231,26 @@ func (t *Tedac) handleConn(conn *minecraft.Conn) {
 		clientData.DeviceOS = protocol.DeviceLinux
 		clientData.DeviceModel = "TEDAC CLIENT"
 
-		data, _ := base64.StdEncoding.DecodeString(clientData.SkinData)
-		switch len(data) {
-		case 32 * 64 * 4:
-			clientData.SkinImageHeight = 32
-			clientData.SkinImageWidth = 64
-		case 64 * 64 * 4:
-			clientData.SkinImageHeight = 64
-			clientData.SkinImageWidth = 64
-		case 128 * 128 * 4:
-			clientData.SkinImageHeight = 128
-			clientData.SkinImageWidth = 128
-		}
+		tedac.UpgradeSkin(&clientData)
 	}
 
+	address, transferred := t.route(conn)
 	serverConn, err := minecraft.Dialer{
 		TokenSource: t.src,
 		ClientData:  clientData,
-	}.Dial("raknet", t.remoteAddress)
+	}.Dial("raknet", address)
 	if err != nil {
 		t.log.Error("error while dialing: " + err.Error())
+		_ = t.listener.Disconnect(conn, "failed to connect to remote server")
+		tedac.Release(conn)
 		return
 	}
+	if _, ok := conn.Protocol().(tedac.Protocol); ok {
+		if t.verticalOffset != 0 {
+			tedac.SetVerticalOffset(conn, t.verticalOffset)
+		}
+		tedac.SetCustomBlockFallbacks(conn, t.customBlocks, t.customBlockFallback)
+	}
 
 	data := serverConn.GameData()
 
@@ -219,278 +270,78 @@ func (t *Tedac) handleConn(conn *minecraft.Conn) {
 	}()
 	g.Wait()
 
-	rid := data.EntityRuntimeID
-	oldMovementSystem := data.PlayerMovementSettings.MovementType == protocol.PlayerMovementModeClient
-	if _, ok := conn.Protocol().(tedac.Protocol); ok {
-		oldMovementSystem = true
-	}
-
-	r := world.Overworld.Range()
-	pos := atomic.NewValue(data.PlayerPosition)
-	lastPos := atomic.NewValue(data.PlayerPosition)
-	yaw, pitch := atomic.NewValue(data.Yaw), atomic.NewValue(data.Pitch)
-
-	startedSneaking, stoppedSneaking := atomic.NewValue(false), atomic.NewValue(false)
-	startedSprinting, stoppedSprinting := atomic.NewValue(false), atomic.NewValue(false)
-	startedGliding, stoppedGliding := atomic.NewValue(false), atomic.NewValue(false)
-	startedSwimming, stoppedSwimming := atomic.NewValue(false), atomic.NewValue(false)
-	startedJumping := atomic.NewValue(false)
-
-	biomeBufferCache := make(map[protocol.ChunkPos][]byte)
-
-	if oldMovementSystem {
-		go func() {
-			t := time.NewTicker(time.Millisecond * 500 / 20)
-			defer t.Stop()
-
-			var tick uint64
-			for range t.C {
-				currentPos, originalPos := pos.Load(), lastPos.Load()
-				lastPos.Store(currentPos)
+	s := newSession(t, conn, serverConn, clientData)
+	t.addSession(s)
+	s.start()
 
-				currentYaw, currentPitch := yaw.Load(), pitch.Load()
+	if t.routing.Policy == RoutingPolicyLobby && len(t.servers) > 0 && !transferred {
+		s.SendServerSelector()
+	}
+}
 
-				inputs := protocol.NewBitset(packet.PlayerAuthInputBitsetSize)
-				if startedSneaking.CompareAndSwap(true, false) {
-					inputs.Set(packet.InputFlagStartSneaking)
-				}
-				if stoppedSneaking.CompareAndSwap(true, false) {
-					inputs.Set(packet.InputFlagStopSneaking)
-				}
-				if startedSprinting.CompareAndSwap(true, false) {
-					inputs.Set(packet.InputFlagStartSprinting)
-				}
-				if stoppedSprinting.CompareAndSwap(true, false) {
-					inputs.Set(packet.InputFlagStopSprinting)
-				}
-				if startedGliding.CompareAndSwap(true, false) {
-					inputs.Set(packet.InputFlagStartGliding)
-				}
-				if stoppedGliding.CompareAndSwap(true, false) {
-					inputs.Set(packet.InputFlagStopGliding)
-				}
-				if startedSwimming.CompareAndSwap(true, false) {
-					inputs.Set(packet.InputFlagStartSwimming)
-				}
-				if stoppedSwimming.CompareAndSwap(true, false) {
-					inputs.Set(packet.InputFlagStopSwimming)
-				}
-				if startedJumping.CompareAndSwap(true, false) {
-					inputs.Set(packet.InputFlagJumping)
-				}
+// Sessions returns a list of all sessions currently proxied by Tedac.
+func (t *Tedac) Sessions() []*Session {
+	t.sessionMu.RLock()
+	defer t.sessionMu.RUnlock()
+	return lo.Values(t.sessions)
+}
 
-				err := serverConn.WritePacket(&packet.PlayerAuthInput{
-					Delta:            currentPos.Sub(originalPos),
-					HeadYaw:          currentYaw,
-					InputData:        inputs,
-					InputMode:        packet.InputModeMouse,
-					InteractionModel: packet.InteractionModelCrosshair,
-					Pitch:            currentPitch,
-					PlayMode:         packet.PlayModeNormal,
-					Position:         currentPos,
-					Tick:             tick,
-					Yaw:              currentYaw,
-				})
-				if err != nil {
-					return
-				}
-				_ = conn.WritePacket(&packet.NetworkChunkPublisherUpdate{ // cry about it
-					Position: protocol.BlockPos{int32(currentPos.X()), int32(currentPos.Y()), int32(currentPos.Z())},
-					Radius:   uint32(data.ChunkRadius) << 4,
-				})
-				tick++
-			}
-		}()
+// SessionByXUID looks up a Session by the XUID of its player. If no such Session exists, false is returned.
+func (t *Tedac) SessionByXUID(xuid string) (*Session, bool) {
+	if xuid == "" {
+		return nil, false
 	}
-	go func() {
-		defer t.listener.Disconnect(conn, "connection lost")
-		defer serverConn.Close()
-		for {
-			pk, err := conn.ReadPacket()
-			if err != nil {
-				return
-			}
-			switch pk := pk.(type) {
-			case *packet.MovePlayer:
-				if !oldMovementSystem {
-					break
-				}
-				pos.Store(pk.Position)
-				yaw.Store(pk.Yaw)
-				pitch.Store(pk.Pitch)
-				continue
-			case *packet.PlayerAction:
-				if !oldMovementSystem {
-					break
-				}
-				switch pk.ActionType {
-				case legacypacket.PlayerActionJump:
-					startedJumping.Store(true)
-					continue
-				case legacypacket.PlayerActionStartSprint:
-					startedSprinting.Store(true)
-					continue
-				case legacypacket.PlayerActionStopSprint:
-					stoppedSprinting.Store(true)
-					continue
-				case legacypacket.PlayerActionStartSneak:
-					startedSneaking.Store(true)
-					continue
-				case legacypacket.PlayerActionStopSneak:
-					stoppedSneaking.Store(true)
-					continue
-				case legacypacket.PlayerActionStartSwimming:
-					startedSwimming.Store(true)
-					continue
-				case legacypacket.PlayerActionStopSwimming:
-					stoppedSwimming.Store(true)
-					continue
-				case legacypacket.PlayerActionStartGlide:
-					startedGliding.Store(true)
-					continue
-				case legacypacket.PlayerActionStopGlide:
-					stoppedGliding.Store(true)
-					continue
-				}
-			}
-			if err := serverConn.WritePacket(pk); err != nil {
-				var disconnect minecraft.DisconnectError
-				if errors.As(errors.Unwrap(err), &disconnect) {
-					_ = t.listener.Disconnect(conn, disconnect.Error())
-				}
-				return
-			}
+	t.sessionMu.RLock()
+	defer t.sessionMu.RUnlock()
+	for _, s := range t.sessions {
+		if s.XUID() == xuid {
+			return s, true
 		}
-	}()
-	go func() {
-		defer serverConn.Close()
-		defer t.listener.Disconnect(conn, "connection lost")
-		for {
-			pk, err := serverConn.ReadPacket()
-			if err != nil {
-				var disconnect minecraft.DisconnectError
-				if errors.As(errors.Unwrap(err), &disconnect) {
-					_ = t.listener.Disconnect(conn, disconnect.Error())
-				}
-				return
-			}
-			switch pk := pk.(type) {
-			case *packet.MovePlayer:
-				if !oldMovementSystem {
-					break
-				}
-				if pk.EntityRuntimeID == rid {
-					pos.Store(pk.Position)
-					yaw.Store(pk.Yaw)
-					pitch.Store(pk.Pitch)
-				}
-			case *packet.MoveActorAbsolute:
-				if !oldMovementSystem {
-					break
-				}
-				if pk.EntityRuntimeID == rid {
-					pos.Store(pk.Position)
-					yaw.Store(pk.Rotation[2])
-					pitch.Store(pk.Rotation[0])
-				}
-			case *packet.MoveActorDelta:
-				if !oldMovementSystem {
-					break
-				}
-				if pk.EntityRuntimeID == rid {
-					pos.Store(pk.Position)
-					yaw.Store(pk.Rotation[2])
-					pitch.Store(pk.Rotation[0])
-				}
-			case *packet.SubChunk:
-				if _, ok := conn.Protocol().(tedac.Protocol); !ok {
-					// Only Tedac clients should receive the old format.
-					break
-				}
-
-				chunkBuf := bytes.NewBuffer(nil)
-				blockEntities := make([]map[string]any, 0)
-				for _, entry := range pk.SubChunkEntries {
-					if entry.Result != protocol.SubChunkResultSuccess {
-						chunkBuf.Write([]byte{
-							chunk.SubChunkVersion,
-							0, // The client will treat this as all air.
-							uint8(entry.Offset[1]),
-						})
-						continue
-					}
-
-					var ind uint8
-					readBuf := bytes.NewBuffer(entry.RawPayload)
-					sub, err := chunk.DecodeSubChunk(airRID, r, readBuf, &ind, chunk.NetworkEncoding)
-					if err != nil {
-						fmt.Println(err)
-						continue
-					}
-
-					var blockEntity map[string]any
-					dec := nbt.NewDecoderWithEncoding(readBuf, nbt.NetworkLittleEndian)
-					for {
-						if err := dec.Decode(&blockEntity); err != nil {
-							break
-						}
-						blockEntities = append(blockEntities, blockEntity)
-					}
-
-					chunkBuf.Write(chunk.EncodeSubChunk(sub, chunk.NetworkEncoding, r, int(ind)))
-				}
-
-				chunkPos := protocol.ChunkPos{pk.Position.X(), pk.Position.Z()}
-				_, _ = chunkBuf.Write(append(biomeBufferCache[chunkPos], 0))
-				delete(biomeBufferCache, chunkPos)
-
-				enc := nbt.NewEncoderWithEncoding(chunkBuf, nbt.NetworkLittleEndian)
-				for _, b := range blockEntities {
-					_ = enc.Encode(b)
-				}
-
-				_ = conn.WritePacket(&packet.LevelChunk{
-					Position:      chunkPos,
-					SubChunkCount: uint32(len(pk.SubChunkEntries)),
-					RawPayload:    append([]byte(nil), chunkBuf.Bytes()...),
-				})
-				continue
-			case *packet.LevelChunk:
-				if pk.SubChunkCount != protocol.SubChunkRequestModeLimitless && pk.SubChunkCount != protocol.SubChunkRequestModeLimited {
-					// No changes to be made here.
-					break
-				}
+	}
+	return nil, false
+}
 
-				if _, ok := conn.Protocol().(tedac.Protocol); !ok {
-					// Only Tedac clients should receive the old format.
-					break
-				}
+// SessionByName looks up a Session by the name of its player. The name is compared case-insensitively. If no such
+// Session exists, false is returned.
+func (t *Tedac) SessionByName(name string) (*Session, bool) {
+	t.sessionMu.RLock()
+	defer t.sessionMu.RUnlock()
+	for _, s := range t.sessions {
+		if strings.EqualFold(s.Name(), name) {
+			return s, true
+		}
+	}
+	return nil, false
+}
 
-				max := r.Height() >> 4
-				if pk.SubChunkCount == protocol.SubChunkRequestModeLimited {
-					max = int(pk.HighestSubChunk)
-				}
+// Kick kicks the player with the XUID passed from Tedac, using the message passed. It returns false if no player
+// with that XUID was connected.
+func (t *Tedac) Kick(xuid, message string) bool {
+	s, ok := t.SessionByXUID(xuid)
+	if ok {
+		s.Kick(message)
+	}
+	return ok
+}
 
-				offsets := make([]protocol.SubChunkOffset, 0, max)
-				for i := 0; i < max; i++ {
-					offsets = append(offsets, protocol.SubChunkOffset{0, int8(i + (r[0] >> 4)), 0})
-				}
+// addSession adds a Session to the registry of Tedac. If a Session of the same player was already present, it is
+// closed first.
+func (t *Tedac) addSession(s *Session) {
+	t.sessionMu.Lock()
+	existing, ok := t.sessions[s.identity()]
+	t.sessions[s.identity()] = s
+	t.sessionMu.Unlock()
 
-				biomeBufferCache[pk.Position] = pk.RawPayload[:len(pk.RawPayload)-1]
-				_ = serverConn.WritePacket(&packet.SubChunkRequest{
-					Position: protocol.SubChunkPos{pk.Position.X(), 0, pk.Position.Z()},
-					Offsets:  offsets,
-				})
-				continue
-			case *packet.Transfer:
-				t.remoteAddress = fmt.Sprintf("%s:%d", pk.Address, pk.Port)
+	if ok {
+		existing.Kick("logged in from another location")
+	}
+}
 
-				pk.Address = t.LocalAddress()
-				pk.Port = t.LocalPort()
-			}
-			if err := conn.WritePacket(pk); err != nil {
-				return
-			}
-		}
-	}()
+// removeSession removes a Session from the registry of Tedac, if it is still registered.
+func (t *Tedac) removeSession(s *Session) {
+	t.sessionMu.Lock()
+	defer t.sessionMu.Unlock()
+	if t.sessions[s.identity()] == s {
+		delete(t.sessions, s.identity())
+	}
 }
@@ -0,0 +1,29 @@
+package tedac
+
+import (
+	"github.com/df-mc/dragonfly/server/block/cube"
+	"github.com/didntpot/tedac/tedac/chunk"
+)
+
+// undergroundBiomes holds the IDs of all biomes in the latest version that only generate below the surface. These
+// are lush caves, dripstone caves and the deep dark.
+var undergroundBiomes = map[uint32]struct{}{
+	187: {},
+	188: {},
+	190: {},
+}
+
+// columnBiome returns the biome of the column at the x and z passed that is shown to v1.12.0 clients, which only
+// support a single biome per column. It is the biome at the highest block within the range passed, as that is
+// where the grass and water colour of the biome are seen. Underground biomes reaching that high, for example in
+// ravines, are skipped in favour of the first surface biome below, if there is one.
+func columnBiome(c *chunk.Chunk, x, z uint8, r cube.Range) uint32 {
+	top := int16(max(min(int(c.HighestBlock(x, z)), r[1]), r[0]))
+	for y := top; y >= int16(r[0]); y-- {
+		biome := c.Biome(x, y, z)
+		if _, ok := undergroundBiomes[biome]; !ok {
+			return biome
+		}
+	}
+	return c.Biome(x, top, z)
+}
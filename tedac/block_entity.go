@@ -0,0 +1,239 @@
+package tedac
+
+import (
+	"bytes"
+	"github.com/df-mc/dragonfly/server/block/cube"
+	"github.com/didntpot/tedac/tedac/legacymappings"
+	"github.com/didntpot/tedac/tedac/legacyprotocol"
+	"github.com/sandertv/gophertunnel/minecraft/nbt"
+	"math"
+	"strings"
+)
+
+// blockEntityDowngraders holds a function for every block entity ID that exists in v1.12.0, which downgrades the
+// NBT of a block entity with that ID from the latest version. Block entities with an ID not present here did not
+// exist in v1.12.0 and are dropped.
+var blockEntityDowngraders = map[string]func(m map[string]any){
+	"Banner":           downgradeBanner,
+	"Beacon":           nil,
+	"Bed":              downgradeBed,
+	"BrewingStand":     downgradeItems,
+	"Cauldron":         downgradeItems,
+	"Chest":            downgradeItems,
+	"CommandBlock":     nil,
+	"Comparator":       nil,
+	"Conduit":          nil,
+	"DaylightDetector": nil,
+	"Dispenser":        downgradeItems,
+	"Dropper":          downgradeItems,
+	"EnchantTable":     nil,
+	"EndGateway":       nil,
+	"EndPortal":        nil,
+	"EnderChest":       nil,
+	"FlowerPot":        downgradeFlowerPot,
+	"Furnace":          downgradeItems,
+	"Hopper":           downgradeItems,
+	"ItemFrame":        downgradeItemFrame,
+	"JukeBox":          downgradeJukebox,
+	"MobSpawner":       nil,
+	"MovingBlock":      nil,
+	"Music":            nil,
+	"PistonArm":        nil,
+	"ShulkerBox":       downgradeItems,
+	"Sign":             downgradeSign,
+	"Skull":            downgradeSkull,
+	"StructureBlock":   nil,
+}
+
+// blockEntityAliases maps the IDs of block entities added after v1.12.0 to the ID of a similar block entity that
+// existed in v1.12.0.
+var blockEntityAliases = map[string]string{
+	"BlastFurnace":  "Furnace",
+	"Smoker":        "Furnace",
+	"HangingSign":   "Sign",
+	"GlowItemFrame": "ItemFrame",
+}
+
+// downgradeBlockEntity downgrades the NBT of a block entity from the latest version to v1.12.0, based on its ID. The
+// map passed may be modified. False is returned if the block entity has no v1.12.0 equivalent.
+func downgradeBlockEntity(m map[string]any) (map[string]any, bool) {
+	id, _ := m["id"].(string)
+	if alias, ok := blockEntityAliases[id]; ok {
+		id = alias
+		m["id"] = id
+	}
+	f, ok := blockEntityDowngraders[id]
+	if !ok {
+		return nil, false
+	}
+	if f != nil {
+		f(m)
+	}
+	return m, true
+}
+
+// upgradeBlockEntity upgrades the NBT of a block entity sent by a v1.12.0 client to the latest version. Only signs
+// are edited by the client, so other block entities are returned as is.
+func upgradeBlockEntity(m map[string]any) map[string]any {
+	if id, _ := m["id"].(string); id == "Sign" {
+		text, _ := m["Text"].(string)
+		delete(m, "Text")
+		m["FrontText"] = signText(text)
+		m["BackText"] = signText("")
+		m["IsWaxed"] = uint8(0)
+	}
+	return m
+}
+
+// signText returns the NBT of one side of a sign in the latest version holding the text passed.
+func signText(text string) map[string]any {
+	return map[string]any{
+		"Text":              text,
+		"TextOwner":         "",
+		"SignTextColor":     int32(-16777216),
+		"IgnoreLighting":    uint8(0),
+		"HideGlowOutline":   uint8(0),
+		"PersistFormatting": uint8(1),
+	}
+}
+
+// downgradeSign merges the front text of a sign into the single text field used in v1.12.0. The back of signs did
+// not exist, so its text is lost.
+func downgradeSign(m map[string]any) {
+	if front, ok := m["FrontText"].(map[string]any); ok {
+		m["Text"], _ = front["Text"].(string)
+	}
+	delete(m, "FrontText")
+	delete(m, "BackText")
+	delete(m, "IsWaxed")
+}
+
+// downgradeBanner removes the banner type, as ominous banners did not yet exist in v1.12.0.
+func downgradeBanner(m map[string]any) {
+	delete(m, "Type")
+	if _, ok := m["Base"]; !ok {
+		m["Base"] = int32(0)
+	}
+}
+
+// downgradeBed makes sure the colour of a bed is present, as beds without a colour crash v1.12.0 clients.
+func downgradeBed(m map[string]any) {
+	if _, ok := m["color"].(uint8); !ok {
+		m["color"] = uint8(14)
+	}
+}
+
+// downgradeSkull converts the rotation of a skull in degrees to the sixteen directions used in v1.12.0.
+func downgradeSkull(m map[string]any) {
+	rot, _ := m["Rotation"].(float32)
+	delete(m, "Rotation")
+	delete(m, "MouthMoving")
+	delete(m, "MouthTickCount")
+	m["Rot"] = uint8(int(math.Round(float64(rot)/22.5)) & 15)
+	if _, ok := m["SkullType"]; !ok {
+		m["SkullType"] = uint8(0)
+	}
+}
+
+// downgradeFlowerPot converts the plant block of a flower pot to the legacy block ID and meta used in v1.12.0.
+func downgradeFlowerPot(m map[string]any) {
+	plant, ok := m["PlantBlock"].(map[string]any)
+	delete(m, "PlantBlock")
+	if !ok {
+		return
+	}
+	name, _ := plant["name"].(string)
+	states, _ := plant["states"].(map[string]any)
+	b := legacymappings.Blocks()[legacymappings.StateToRuntimeID(name, states)]
+	m["item"], m["mData"] = b.LegacyID, int32(b.Data)
+}
+
+// downgradeItemFrame downgrades the item held by an item frame.
+func downgradeItemFrame(m map[string]any) {
+	if item, ok := m["Item"].(map[string]any); ok {
+		m["Item"] = downgradeItemNBT(item)
+	}
+}
+
+// downgradeJukebox downgrades the record played by a jukebox.
+func downgradeJukebox(m map[string]any) {
+	if item, ok := m["RecordItem"].(map[string]any); ok {
+		m["RecordItem"] = downgradeItemNBT(item)
+	}
+}
+
+// downgradeItems downgrades the items held by a container block entity.
+func downgradeItems(m map[string]any) {
+	items, ok := m["Items"].([]any)
+	if !ok {
+		return
+	}
+	downgraded := make([]any, 0, len(items))
+	for _, item := range items {
+		if item, ok := item.(map[string]any); ok {
+			downgraded = append(downgraded, downgradeItemNBT(item))
+		}
+	}
+	m["Items"] = downgraded
+}
+
+// downgradeItemNBT downgrades an item stored in NBT, which is identified by name in the latest version and by a
+// numerical ID in v1.12.0.
+func downgradeItemNBT(m map[string]any) map[string]any {
+	name, ok := m["Name"].(string)
+	if !ok {
+		return m
+	}
+	delete(m, "Name")
+	delete(m, "Block")
+	delete(m, "WasPickedUp")
+	if !strings.HasPrefix(name, "minecraft:") {
+		name = "minecraft:" + name
+	}
+	meta, _ := m["Damage"].(int16)
+	id, meta, ok := legacymappings.ItemByName(name, meta)
+	m["id"] = id
+
+	tag, _ := m["tag"].(map[string]any)
+	meta, tag = legacyprotocol.DowngradeItemNBT(name, meta, tag)
+	if !ok {
+		tag = withItemName(tag, name)
+	}
+	m["Damage"] = meta
+	if len(tag) > 0 {
+		m["tag"] = tag
+	}
+	return m
+}
+
+// downgradeBlockEntities downgrades the block entity NBT trailing the payload of a LevelChunk. The payload starts
+// with the amount of border blocks, which is always zero. The vertical offset passed is subtracted from the Y of
+// every block entity, and block entities outside the range passed are dropped.
+func downgradeBlockEntities(payload []byte, r cube.Range, verticalOffset int32) []byte {
+	if len(payload) == 0 {
+		return payload
+	}
+	buf := bytes.NewBuffer(nil)
+	buf.WriteByte(payload[0])
+
+	dec := nbt.NewDecoderWithEncoding(bytes.NewBuffer(payload[1:]), nbt.NetworkLittleEndian)
+	enc := nbt.NewEncoderWithEncoding(buf, nbt.NetworkLittleEndian)
+	for {
+		var m map[string]any
+		if err := dec.Decode(&m); err != nil {
+			break
+		}
+		m, ok := downgradeBlockEntity(m)
+		if !ok {
+			continue
+		}
+		if y, ok := m["y"].(int32); ok {
+			if y -= verticalOffset; int(y) < r[0] || int(y) > r[1] {
+				continue
+			}
+			m["y"] = y
+		}
+		_ = enc.Encode(m)
+	}
+	return buf.Bytes()
+}
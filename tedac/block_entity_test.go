@@ -0,0 +1,26 @@
+package tedac
+
+import "testing"
+
+func TestDowngradeFlowerPot(t *testing.T) {
+	tests := []struct {
+		name     string
+		states   map[string]any
+		wantID   int16
+		wantMeta int32
+	}{
+		{name: "minecraft:poppy", wantID: 38, wantMeta: 0},
+		{name: "minecraft:blue_orchid", wantID: 38, wantMeta: 1},
+		{name: "minecraft:birch_sapling", states: map[string]any{"age_bit": uint8(0)}, wantID: 6, wantMeta: 2},
+		{name: "minecraft:dandelion", wantID: 37, wantMeta: 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			m := map[string]any{"PlantBlock": map[string]any{"name": test.name, "states": test.states}}
+			downgradeFlowerPot(m)
+			if m["item"] != test.wantID || m["mData"] != test.wantMeta {
+				t.Errorf("got (%v, %v), want (%v, %v)", m["item"], m["mData"], test.wantID, test.wantMeta)
+			}
+		})
+	}
+}
@@ -0,0 +1,136 @@
+package tedac
+
+import (
+	"github.com/didntpot/tedac/tedac/legacyprotocol"
+	"github.com/sandertv/gophertunnel/minecraft/protocol"
+	"github.com/sandertv/gophertunnel/minecraft/protocol/packet"
+	"math"
+)
+
+// legacyArgTypes maps the argument types of command parameters in the latest version to their v1.12.0 equivalent.
+// Types not present in the map are shown as a string.
+var legacyArgTypes = map[uint32]uint32{
+	protocol.CommandArgTypeInt:             legacyprotocol.CommandArgTypeInt,
+	protocol.CommandArgTypeFloat:           legacyprotocol.CommandArgTypeFloat,
+	protocol.CommandArgTypeValue:           legacyprotocol.CommandArgTypeValue,
+	protocol.CommandArgTypeWildcardInt:     legacyprotocol.CommandArgTypeWildcardInt,
+	protocol.CommandArgTypeOperator:        legacyprotocol.CommandArgTypeOperator,
+	protocol.CommandArgTypeCompareOperator: legacyprotocol.CommandArgTypeOperator,
+	protocol.CommandArgTypeTarget:          legacyprotocol.CommandArgTypeTarget,
+	protocol.CommandArgTypeWildcardTarget:  legacyprotocol.CommandArgTypeWildcardTarget,
+	protocol.CommandArgTypeFilepath:        legacyprotocol.CommandArgTypeFilepath,
+	protocol.CommandArgTypeIntegerRange:    legacyprotocol.CommandArgTypeString,
+	protocol.CommandArgTypeEquipmentSlots:  legacyprotocol.CommandArgTypeString,
+	protocol.CommandArgTypeString:          legacyprotocol.CommandArgTypeString,
+	protocol.CommandArgTypeBlockPosition:   legacyprotocol.CommandArgTypePosition,
+	protocol.CommandArgTypePosition:        legacyprotocol.CommandArgTypePosition,
+	protocol.CommandArgTypeMessage:         legacyprotocol.CommandArgTypeMessage,
+	protocol.CommandArgTypeRawText:         legacyprotocol.CommandArgTypeRawText,
+	protocol.CommandArgTypeJSON:            legacyprotocol.CommandArgTypeJSON,
+	protocol.CommandArgTypeBlockStates:     legacyprotocol.CommandArgTypeString,
+	protocol.CommandArgTypeCommand:         legacyprotocol.CommandArgTypeCommand,
+}
+
+// downgradeCommands downgrades the commands of an AvailableCommands packet to their v1.12.0 equivalent, resolving
+// the enums, dynamic enums, aliases and suffixes that the latest version references by index.
+func downgradeCommands(pk *packet.AvailableCommands) []legacyprotocol.Command {
+	enums := make([]legacyprotocol.CommandEnum, 0, len(pk.Enums))
+	for _, e := range pk.Enums {
+		options := make([]string, 0, len(e.ValueIndices))
+		for _, i := range e.ValueIndices {
+			if int(i) < len(pk.EnumValues) {
+				options = append(options, pk.EnumValues[i])
+			}
+		}
+		enums = append(enums, legacyprotocol.CommandEnum{Type: e.Type, Options: options})
+	}
+	dynamicEnums := make([]legacyprotocol.CommandEnum, 0, len(pk.DynamicEnums))
+	for _, e := range pk.DynamicEnums {
+		dynamicEnums = append(dynamicEnums, legacyprotocol.CommandEnum{Type: e.Type, Options: e.Values, Dynamic: true})
+	}
+
+	commands := make([]legacyprotocol.Command, 0, len(pk.Commands))
+	for _, c := range pk.Commands {
+		command := legacyprotocol.Command{
+			Name:            c.Name,
+			Description:     c.Description,
+			Flags:           byte(c.Flags),
+			PermissionLevel: c.PermissionLevel,
+		}
+		if c.AliasesOffset != math.MaxUint32 && int(c.AliasesOffset) < len(enums) {
+			command.Aliases = enums[c.AliasesOffset].Options
+		}
+		for _, o := range c.Overloads {
+			if o.Chaining {
+				// Chained sub commands did not exist in v1.12.0, so there is no way to show these overloads.
+				continue
+			}
+			overload := legacyprotocol.CommandOverload{Parameters: make([]legacyprotocol.CommandParameter, 0, len(o.Parameters))}
+			for _, p := range o.Parameters {
+				overload.Parameters = append(overload.Parameters, downgradeCommandParameter(p, enums, dynamicEnums, pk.Suffixes))
+			}
+			command.Overloads = append(command.Overloads, overload)
+		}
+		commands = append(commands, command)
+	}
+	return commands
+}
+
+// downgradeCommandParameter downgrades a single command parameter to its v1.12.0 equivalent. Enums without any
+// options and parameters of unknown types fall back to strings.
+func downgradeCommandParameter(p protocol.CommandParameter, enums, dynamicEnums []legacyprotocol.CommandEnum, suffixes []string) legacyprotocol.CommandParameter {
+	param := legacyprotocol.CommandParameter{
+		Name:                p.Name,
+		Type:                legacyprotocol.CommandArgValid | legacyprotocol.CommandArgTypeString,
+		Optional:            p.Optional,
+		CollapseEnumOptions: p.Options&protocol.ParamOptionCollapseEnum != 0,
+	}
+	index := p.Type & 0xffff
+	switch {
+	case p.Type&protocol.CommandArgSoftEnum != 0:
+		if int(index) < len(dynamicEnums) {
+			// Dynamic enums may be empty and filled later on using the UpdateSoftEnum packet.
+			param.Enum = dynamicEnums[index]
+		}
+	case p.Type&protocol.CommandArgEnum != 0:
+		if int(index) < len(enums) && len(enums[index].Options) != 0 {
+			param.Enum = enums[index]
+		}
+	case p.Type&protocol.CommandArgSuffixed != 0:
+		if int(index) < len(suffixes) {
+			param.Suffix = suffixes[index]
+		}
+	default:
+		if t, ok := legacyArgTypes[index]; ok {
+			param.Type = legacyprotocol.CommandArgValid | t
+		}
+	}
+	return param
+}
+
+// softEnumTypes returns the types of all soft enums used by the parameters of the commands passed. v1.12.0 clients
+// only receive the soft enums that are used by a parameter, so the other soft enums cannot be updated for them.
+func softEnumTypes(commands []legacyprotocol.Command) map[string]struct{} {
+	types := make(map[string]struct{})
+	for _, c := range commands {
+		for _, o := range c.Overloads {
+			for _, p := range o.Parameters {
+				if p.Enum.Dynamic {
+					types[p.Enum.Type] = struct{}{}
+				}
+			}
+		}
+	}
+	return types
+}
+
+// downgradeSoftEnumUpdate checks if an UpdateSoftEnum packet can be sent to the v1.12.0 client as is, which uses the
+// same actions as the latest version. False is returned if the soft enum updated was not sent to the client in the
+// AvailableCommands packet, or if the action did not yet exist.
+func (s *state) downgradeSoftEnumUpdate(pk *packet.UpdateSoftEnum) bool {
+	if pk.ActionType > packet.SoftEnumActionSet {
+		return false
+	}
+	_, ok := s.softEnums.Load()[pk.EnumType]
+	return ok
+}
@@ -0,0 +1,43 @@
+package tedac
+
+import (
+	"github.com/sandertv/gophertunnel/minecraft/protocol"
+	"github.com/sandertv/gophertunnel/minecraft/protocol/packet"
+	"testing"
+)
+
+func TestDowngradeSoftEnumUpdate(t *testing.T) {
+	s := &state{}
+	s.softEnums.Store(softEnumTypes(downgradeCommands(&packet.AvailableCommands{
+		DynamicEnums: []protocol.DynamicEnum{{Type: "players"}, {Type: "unused"}},
+		Commands: []protocol.Command{{
+			Name:          "msg",
+			AliasesOffset: ^uint32(0),
+			Overloads: []protocol.CommandOverload{{
+				Parameters: []protocol.CommandParameter{{
+					Name: "target",
+					Type: protocol.CommandArgValid | protocol.CommandArgSoftEnum,
+				}},
+			}},
+		}},
+	})))
+
+	tests := []struct {
+		name string
+		pk   *packet.UpdateSoftEnum
+		want bool
+	}{
+		{name: "add", pk: &packet.UpdateSoftEnum{EnumType: "players", ActionType: packet.SoftEnumActionAdd}, want: true},
+		{name: "set", pk: &packet.UpdateSoftEnum{EnumType: "players", ActionType: packet.SoftEnumActionSet}, want: true},
+		{name: "unknown action", pk: &packet.UpdateSoftEnum{EnumType: "players", ActionType: packet.SoftEnumActionSet + 1}},
+		{name: "unused enum", pk: &packet.UpdateSoftEnum{EnumType: "unused", ActionType: packet.SoftEnumActionAdd}},
+		{name: "unknown enum", pk: &packet.UpdateSoftEnum{EnumType: "unknown", ActionType: packet.SoftEnumActionAdd}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := s.downgradeSoftEnumUpdate(test.pk); got != test.want {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package tedac
+
+import (
+	"github.com/didntpot/tedac/tedac/legacymappings"
+	"github.com/didntpot/tedac/tedac/legacyprotocol"
+	"github.com/sandertv/gophertunnel/minecraft/protocol"
+	"maps"
+	"sync"
+)
+
+// entities keeps track of the types of the entities added by the server, so that the metadata sent for them later
+// on is downgraded the same way as when they were added.
+type entities struct {
+	mu sync.Mutex
+	// types holds the original entity type of every entity, keyed by runtime ID.
+	types map[uint64]string
+	// runtimeIDs holds the runtime ID of every entity, keyed by unique ID.
+	runtimeIDs map[int64]uint64
+}
+
+// newEntities returns a new entities without any entities.
+func newEntities() *entities {
+	return &entities{types: make(map[uint64]string), runtimeIDs: make(map[int64]uint64)}
+}
+
+// add keeps track of the type of an entity added by the server.
+func (e *entities) add(uniqueID int64, runtimeID uint64, entityType string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.types[runtimeID], e.runtimeIDs[uniqueID] = entityType, runtimeID
+}
+
+// entityType returns the type of the entity with the runtime ID passed. False is returned if the entity was not
+// added using add, such as players.
+func (e *entities) entityType(runtimeID uint64) (string, bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	entityType, ok := e.types[runtimeID]
+	return entityType, ok
+}
+
+// remove stops keeping track of the entity with the unique ID passed.
+func (e *entities) remove(uniqueID int64) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if runtimeID, ok := e.runtimeIDs[uniqueID]; ok {
+		delete(e.types, runtimeID)
+		delete(e.runtimeIDs, uniqueID)
+	}
+}
+
+// downgradeEntity downgrades the type of an entity added by the server to its v1.12.0 equivalent. Entity types
+// added after v1.12.0 are replaced by a similar entity type, which is scaled to roughly the size of the original
+// entity and named after it if the entity does not have a name yet. False is returned if the entity type has no
+// v1.12.0 equivalent, in which case the entity should not be sent to the client. The metadata passed is not
+// modified.
+func downgradeEntity(entityType string, metadata map[uint32]any) (string, map[uint32]any, bool) {
+	legacyType, scale, ok := legacymappings.EntityType(entityType)
+	if !ok {
+		return "", nil, false
+	}
+	if legacyType == entityType {
+		return entityType, metadata, true
+	}
+	metadata = maps.Clone(metadata)
+	if metadata == nil {
+		metadata = make(map[uint32]any)
+	}
+	if scale != 1 {
+		current, ok := metadata[protocol.EntityDataKeyScale].(float32)
+		if !ok {
+			current = 1
+		}
+		metadata[protocol.EntityDataKeyScale] = current * scale
+	}
+	if name, _ := metadata[protocol.EntityDataKeyName].(string); name == "" {
+		metadata[protocol.EntityDataKeyName] = readableName(entityType)
+	}
+	return legacyType, metadata, true
+}
+
+// downgradeEntityMetadata downgrades the metadata of an entity with the type passed to v1.12.0, including the block
+// runtime IDs held by minecarts, endermen and falling blocks. The entity type may be empty if it is not known.
+func (s *state) downgradeEntityMetadata(entityType string, metadata map[uint32]any) map[uint32]any {
+	data := legacyprotocol.DowngradeEntityMetadata(metadata)
+	// The keys holding block runtime IDs are the same in both versions.
+	for _, key := range []uint32{protocol.EntityDataKeyDisplayTileRuntimeID, protocol.EntityDataKeyCarryBlockRuntimeID} {
+		if rid, ok := data[key].(int32); ok {
+			data[key] = int32(s.downgradeBlockRuntimeID(uint32(rid)))
+		}
+	}
+	if rid, ok := data[protocol.EntityDataKeyVariant].(int32); ok && entityType == "minecraft:falling_block" {
+		data[protocol.EntityDataKeyVariant] = int32(s.downgradeBlockRuntimeID(uint32(rid)))
+	}
+	return data
+}
@@ -0,0 +1,681 @@
+package tedac
+
+import (
+	"github.com/didntpot/tedac/tedac/latestmappings"
+	"github.com/didntpot/tedac/tedac/legacyprotocol"
+	"github.com/didntpot/tedac/tedac/legacyprotocol/legacypacket"
+	"github.com/sandertv/gophertunnel/minecraft/protocol"
+	"github.com/sandertv/gophertunnel/minecraft/protocol/packet"
+	"sync"
+)
+
+const (
+	// windowCraftingAddIngredient and windowCraftingRemoveIngredient are the window IDs used by v1.12.0 clients
+	// for actions changing the crafting grid.
+	windowCraftingAddIngredient    = -2
+	windowCraftingRemoveIngredient = -3
+	// windowCraftingResult is the window ID used by v1.12.0 clients for the action taking out a crafting result.
+	windowCraftingResult = -4
+	// windowCraftingUseIngredient is the window ID used by v1.12.0 clients for actions consuming the ingredients
+	// of a recipe. These actions duplicate the crafting grid changes and are ignored.
+	windowCraftingUseIngredient = -5
+	// windowContainerDropContents is the window ID used by v1.12.0 clients when the contents of a closed
+	// container are dropped. These actions are ignored.
+	windowContainerDropContents = -100
+)
+
+const (
+	// creativeSlotDelete and creativeSlotCreate are the inventory slots used by creative inventory actions to
+	// describe deleting and creating an item respectively.
+	creativeSlotDelete = 0
+	creativeSlotCreate = 1
+)
+
+const (
+	// smallCraftingGridOffset and bigCraftingGridOffset are the first slots of the crafting grid in the UI
+	// container, for the inventory and crafting table respectively.
+	smallCraftingGridOffset = 28
+	bigCraftingGridOffset   = 32
+	// createdOutputSlot is the slot in the UI container that holds the item crafted.
+	createdOutputSlot = 50
+)
+
+// inventory keeps track of the contents of all windows of a connection, so that the legacy inventory
+// transactions of the client can be translated to item stack requests, which reference the stack network IDs
+// of the items the server sent.
+type inventory struct {
+	mu sync.Mutex
+
+	// windows holds the contents of every window known, keyed by window ID and then by slot.
+	windows map[uint32]map[uint32]protocol.ItemInstance
+	// containerTypes holds the container type of every window opened by the server.
+	containerTypes map[uint32]byte
+
+	creativeItems []protocol.CreativeItem
+	recipes       []recipe
+
+	// requestID is the ID of the last item stack request sent.
+	requestID int32
+	// pending holds the slots changed by every item stack request that has not yet been responded to.
+	pending map[int32][]pendingSlot
+}
+
+// recipe is a crafting recipe sent by the server that may be crafted using the crafting grid.
+type recipe struct {
+	networkID uint32
+	output    []protocol.ItemStack
+}
+
+// pendingSlot is a slot changed by the client in an item stack request. It holds the item previously in the slot,
+// so that it can be restored if the request is rejected.
+type pendingSlot struct {
+	window, slot uint32
+	container    byte
+	requestSlot  byte
+	old          protocol.ItemInstance
+}
+
+// stackSlot is a slot referenced by an item stack request, along with the item and amount either taken out of it
+// or put into it.
+type stackSlot struct {
+	info  protocol.StackRequestSlotInfo
+	item  protocol.ItemStack
+	count int
+}
+
+// newInventory returns a new, empty inventory.
+func newInventory() *inventory {
+	return &inventory{
+		windows:        make(map[uint32]map[uint32]protocol.ItemInstance),
+		containerTypes: make(map[uint32]byte),
+		requestID:      1,
+		pending:        make(map[int32][]pendingSlot),
+	}
+}
+
+// setContent replaces the contents of the window passed.
+func (inv *inventory) setContent(window uint32, content []protocol.ItemInstance) {
+	inv.mu.Lock()
+	defer inv.mu.Unlock()
+	slots := make(map[uint32]protocol.ItemInstance, len(content))
+	for i, item := range content {
+		slots[uint32(i)] = item
+	}
+	inv.windows[window] = slots
+}
+
+// setSlot changes a single slot of the window passed.
+func (inv *inventory) setSlot(window, slot uint32, item protocol.ItemInstance) {
+	inv.mu.Lock()
+	defer inv.mu.Unlock()
+	inv.slots(window)[slot] = item
+}
+
+// open registers a window opened by the server with the container type passed.
+func (inv *inventory) open(window, containerType byte) {
+	inv.mu.Lock()
+	defer inv.mu.Unlock()
+	inv.containerTypes[uint32(window)] = containerType
+}
+
+// close forgets about a window that was closed, along with its contents.
+func (inv *inventory) close(window byte) {
+	inv.mu.Lock()
+	defer inv.mu.Unlock()
+	delete(inv.containerTypes, uint32(window))
+	switch window {
+	case legacyprotocol.WindowIDInventory, legacyprotocol.WindowIDOffHand, legacyprotocol.WindowIDArmour, legacyprotocol.WindowIDUI:
+	default:
+		delete(inv.windows, uint32(window))
+	}
+}
+
+// setCreativeItems sets the items in the creative inventory.
+func (inv *inventory) setCreativeItems(items []protocol.CreativeItem) {
+	inv.mu.Lock()
+	defer inv.mu.Unlock()
+	inv.creativeItems = items
+}
+
+// addRecipes adds the crafting recipes passed that may be crafted using a crafting grid.
+func (inv *inventory) addRecipes(recipes []protocol.Recipe, clear bool) {
+	inv.mu.Lock()
+	defer inv.mu.Unlock()
+	if clear {
+		inv.recipes = nil
+	}
+	for _, r := range recipes {
+		switch r := r.(type) {
+		case *protocol.ShapedRecipe:
+			inv.recipes = append(inv.recipes, recipe{networkID: r.RecipeNetworkID, output: r.Output})
+		case *protocol.ShapelessRecipe:
+			inv.recipes = append(inv.recipes, recipe{networkID: r.RecipeNetworkID, output: r.Output})
+		}
+	}
+}
+
+// slots returns the slots of the window passed, creating them if needed. inv.mu must be held.
+func (inv *inventory) slots(window uint32) map[uint32]protocol.ItemInstance {
+	slots, ok := inv.windows[window]
+	if !ok {
+		slots = make(map[uint32]protocol.ItemInstance)
+		inv.windows[window] = slots
+	}
+	return slots
+}
+
+// containerOf returns the container ID and slot used in item stack requests for the legacy window and slot passed.
+// False is returned if the window is not supported. inv.mu must be held.
+func (inv *inventory) containerOf(window int32, slot uint32) (byte, byte, bool) {
+	switch window {
+	case legacyprotocol.WindowIDInventory:
+		if slot < 9 {
+			return protocol.ContainerHotBar, byte(slot), true
+		}
+		return protocol.ContainerInventory, byte(slot), slot < 36
+	case legacyprotocol.WindowIDOffHand:
+		return protocol.ContainerOffhand, 1, true
+	case legacyprotocol.WindowIDArmour:
+		return protocol.ContainerArmor, byte(slot), slot < 4
+	case legacyprotocol.WindowIDUI:
+		switch {
+		case slot == 0:
+			return protocol.ContainerCursor, 0, true
+		case slot == createdOutputSlot:
+			return protocol.ContainerCreatedOutput, createdOutputSlot, true
+		case slot >= smallCraftingGridOffset && slot < createdOutputSlot:
+			return protocol.ContainerCraftingInput, byte(slot), true
+		}
+		return 0, 0, false
+	case windowCraftingAddIngredient, windowCraftingRemoveIngredient:
+		offset := uint32(smallCraftingGridOffset)
+		if inv.workbenchOpen() {
+			offset = bigCraftingGridOffset
+		}
+		return protocol.ContainerCraftingInput, byte(slot + offset), true
+	}
+	if window < 0 {
+		return 0, 0, false
+	}
+	containerType, ok := inv.containerTypes[uint32(window)]
+	if !ok {
+		return 0, 0, false
+	}
+	switch containerType {
+	case protocol.ContainerTypeContainer, protocol.ContainerTypeDispenser, protocol.ContainerTypeDropper, protocol.ContainerTypeHopper:
+		return protocol.ContainerLevelEntity, byte(slot), true
+	case protocol.ContainerTypeFurnace, protocol.ContainerTypeBlastFurnace, protocol.ContainerTypeSmoker:
+		switch slot {
+		case 0:
+			return protocol.ContainerFurnaceIngredient, 0, true
+		case 1:
+			return protocol.ContainerFurnaceFuel, 1, true
+		case 2:
+			return protocol.ContainerFurnaceResult, 2, true
+		}
+	}
+	return 0, 0, false
+}
+
+// workbenchOpen checks if the client currently has a crafting table opened. inv.mu must be held.
+func (inv *inventory) workbenchOpen() bool {
+	for _, containerType := range inv.containerTypes {
+		if containerType == protocol.ContainerTypeWorkbench {
+			return true
+		}
+	}
+	return false
+}
+
+// windowOf returns the window ID under which the items of a legacy window are tracked. The crafting grid is part
+// of the UI window.
+func windowOf(window int32) uint32 {
+	if window == windowCraftingAddIngredient || window == windowCraftingRemoveIngredient {
+		return legacyprotocol.WindowIDUI
+	}
+	return uint32(window)
+}
+
+// request translates the actions of a legacy normal inventory transaction to an item stack request. False is
+// returned if the actions could not be translated, in which case the transaction should be sent as is.
+func (inv *inventory) request(m *latestmappings.Mappings, actions []legacyprotocol.InventoryAction) (protocol.ItemStackRequest, bool) {
+	inv.mu.Lock()
+	defer inv.mu.Unlock()
+
+	oldItems, newItems := inv.upgradeActions(m, actions)
+
+	requestID := inv.requestID - 2
+	var (
+		stackActions []protocol.StackRequestAction
+		changed      []pendingSlot
+		accepted     bool
+
+		sources, destinations []*stackSlot
+		drops, deletions      []*stackSlot
+		grid                  []*stackSlot
+
+		result, created protocol.ItemStack
+	)
+	defer func() {
+		if !accepted {
+			// The slots changed so far must be rolled back, as the transaction may still be sent as is.
+			inv.restore(changed)
+		}
+	}()
+	for i, action := range actions {
+		window := windowOf(action.WindowID)
+		oldItem, newItem := oldItems[i], newItems[i]
+		switch action.SourceType {
+		case legacyprotocol.InventoryActionSourceWorld:
+			if !emptyItem(newItem) {
+				drops = append(drops, &stackSlot{item: newItem, count: int(newItem.Count)})
+			}
+			continue
+		case legacyprotocol.InventoryActionSourceCreative:
+			switch action.InventorySlot {
+			case creativeSlotDelete:
+				deletions = append(deletions, &stackSlot{item: newItem, count: int(newItem.Count)})
+			case creativeSlotCreate:
+				created = oldItem
+			}
+			continue
+		case legacyprotocol.InventoryActionSourceTODO:
+			switch action.WindowID {
+			case windowCraftingResult:
+				result = oldItem
+				if emptyItem(result) {
+					result = newItem
+				}
+				continue
+			case windowCraftingUseIngredient, windowContainerDropContents:
+				continue
+			case windowCraftingAddIngredient, windowCraftingRemoveIngredient:
+			default:
+				return protocol.ItemStackRequest{}, false
+			}
+		case legacyprotocol.InventoryActionSourceContainer:
+			if action.WindowID == legacyprotocol.WindowIDUI && action.InventorySlot == createdOutputSlot {
+				// The crafting output is handled through the crafting result action.
+				continue
+			}
+		default:
+			return protocol.ItemStackRequest{}, false
+		}
+
+		container, slot, ok := inv.containerOf(action.WindowID, action.InventorySlot)
+		if !ok {
+			return protocol.ItemStackRequest{}, false
+		}
+		windowSlot := action.InventorySlot
+		if container == protocol.ContainerCraftingInput {
+			windowSlot = uint32(slot)
+		}
+		current := inv.slots(window)[windowSlot]
+		info := protocol.StackRequestSlotInfo{
+			Container:      protocol.FullContainerName{ContainerID: container},
+			Slot:           slot,
+			StackNetworkID: current.StackNetworkID,
+		}
+
+		removed, added := 0, 0
+		if sameItem(oldItem, newItem) {
+			if newItem.Count < oldItem.Count {
+				removed = int(oldItem.Count - newItem.Count)
+			} else {
+				added = int(newItem.Count - oldItem.Count)
+			}
+		} else {
+			removed, added = int(oldItem.Count), int(newItem.Count)
+		}
+		if removed > 0 {
+			s := &stackSlot{info: info, item: oldItem, count: removed}
+			if container == protocol.ContainerCraftingInput {
+				grid = append(grid, s)
+			} else {
+				sources = append(sources, s)
+			}
+		}
+		if added > 0 {
+			destinations = append(destinations, &stackSlot{info: info, item: newItem, count: added})
+		}
+
+		networkID := int32(0)
+		if sameItem(current.Stack, newItem) {
+			networkID = current.StackNetworkID
+		}
+		changed = append(changed, pendingSlot{window: window, slot: windowSlot, container: container, requestSlot: slot, old: current})
+		inv.slots(window)[windowSlot] = protocol.ItemInstance{StackNetworkID: networkID, Stack: newItem}
+	}
+
+	output := protocol.StackRequestSlotInfo{
+		Container:      protocol.FullContainerName{ContainerID: protocol.ContainerCreatedOutput},
+		Slot:           createdOutputSlot,
+		StackNetworkID: requestID,
+	}
+	switch {
+	case !emptyItem(result):
+		r, ok := inv.recipeFor(result)
+		if !ok {
+			return protocol.ItemStackRequest{}, false
+		}
+		times := max(int(result.Count)/max(int(r.output[0].Count), 1), 1)
+		stackActions = append(stackActions,
+			&protocol.CraftRecipeStackRequestAction{RecipeNetworkID: r.networkID, NumberOfCrafts: byte(times)},
+			&protocol.CraftResultsDeprecatedStackRequestAction{ResultItems: r.output, TimesCrafted: byte(times)},
+		)
+		for _, s := range grid {
+			a := &protocol.ConsumeStackRequestAction{}
+			a.Count, a.Source = byte(s.count), s.info
+			stackActions = append(stackActions, a)
+		}
+		grid = nil
+		sources = append(sources, &stackSlot{info: output, item: result, count: int(result.Count)})
+	case !emptyItem(created):
+		id, ok := inv.creativeItemFor(created)
+		if !ok {
+			return protocol.ItemStackRequest{}, false
+		}
+		stackActions = append(stackActions, &protocol.CraftCreativeStackRequestAction{CreativeItemNetworkID: id, NumberOfCrafts: 1})
+		sources = append(sources, &stackSlot{info: output, item: created, count: int(created.Count)})
+	}
+	sources = append(sources, grid...)
+
+	stackActions = append(stackActions, swaps(&sources, &destinations)...)
+	for _, dst := range destinations {
+		for _, src := range sources {
+			if dst.count == 0 {
+				break
+			}
+			if src.count == 0 || !sameItem(src.item, dst.item) {
+				continue
+			}
+			n := min(src.count, dst.count)
+			a := &protocol.PlaceStackRequestAction{}
+			a.Count, a.Source, a.Destination = byte(n), src.info, dst.info
+			stackActions = append(stackActions, a)
+			src.count, dst.count = src.count-n, dst.count-n
+		}
+		if dst.count != 0 {
+			return protocol.ItemStackRequest{}, false
+		}
+	}
+	dropped, ok := release(drops, sources, func(n byte, src protocol.StackRequestSlotInfo) protocol.StackRequestAction {
+		return &protocol.DropStackRequestAction{Count: n, Source: src}
+	})
+	if !ok {
+		return protocol.ItemStackRequest{}, false
+	}
+	destroyed, ok := release(deletions, sources, func(n byte, src protocol.StackRequestSlotInfo) protocol.StackRequestAction {
+		return &protocol.DestroyStackRequestAction{Count: n, Source: src}
+	})
+	if !ok {
+		return protocol.ItemStackRequest{}, false
+	}
+	stackActions = append(append(stackActions, dropped...), destroyed...)
+	for _, src := range sources {
+		if src.count != 0 && src.info.Container.ContainerID != protocol.ContainerCreatedOutput {
+			// The transaction was not balanced, so there's no way we can translate it properly.
+			return protocol.ItemStackRequest{}, false
+		}
+	}
+	if len(stackActions) == 0 {
+		return protocol.ItemStackRequest{}, false
+	}
+
+	accepted = true
+	inv.requestID = requestID
+	inv.pending[requestID] = changed
+	return protocol.ItemStackRequest{RequestID: requestID, Actions: stackActions}, true
+}
+
+// swaps finds pairs of slots of which the items were swapped and returns swap actions for them. The slots swapped
+// are removed from the sources and destinations passed.
+func swaps(sources, destinations *[]*stackSlot) []protocol.StackRequestAction {
+	var actions []protocol.StackRequestAction
+	for _, a := range *sources {
+		for _, b := range *sources {
+			if a == b || a.count == 0 || b.count == 0 || sameItem(a.item, b.item) {
+				continue
+			}
+			aDst, bDst := findSlot(*destinations, a.info, b.item, b.count), findSlot(*destinations, b.info, a.item, a.count)
+			if aDst == nil || bDst == nil {
+				continue
+			}
+			actions = append(actions, &protocol.SwapStackRequestAction{Source: a.info, Destination: b.info})
+			a.count, b.count, aDst.count, bDst.count = 0, 0, 0, 0
+		}
+	}
+	return actions
+}
+
+// findSlot finds the slot with the info passed in the slots passed that the item and count passed was added to.
+func findSlot(slots []*stackSlot, info protocol.StackRequestSlotInfo, item protocol.ItemStack, count int) *stackSlot {
+	for _, s := range slots {
+		if s.info == info && s.count == count && sameItem(s.item, item) {
+			return s
+		}
+	}
+	return nil
+}
+
+// release takes the items in the slots passed out of the sources passed, using the action returned by f for every
+// source an item is taken out of. False is returned if the sources did not hold enough items.
+func release(slots, sources []*stackSlot, f func(n byte, src protocol.StackRequestSlotInfo) protocol.StackRequestAction) ([]protocol.StackRequestAction, bool) {
+	var actions []protocol.StackRequestAction
+	for _, s := range slots {
+		for _, src := range sources {
+			if s.count == 0 {
+				break
+			}
+			if src.count == 0 || !sameItem(src.item, s.item) {
+				continue
+			}
+			n := min(src.count, s.count)
+			actions = append(actions, f(byte(n), src.info))
+			src.count, s.count = src.count-n, s.count-n
+		}
+		if s.count != 0 {
+			return nil, false
+		}
+	}
+	return actions, true
+}
+
+// recipeFor finds a recipe with the result passed as its first output. inv.mu must be held.
+func (inv *inventory) recipeFor(result protocol.ItemStack) (recipe, bool) {
+	for _, r := range inv.recipes {
+		if len(r.output) == 0 {
+			continue
+		}
+		out := r.output[0]
+		if out.NetworkID == result.NetworkID && (out.MetadataValue == result.MetadataValue || out.MetadataValue == 0x7fff) {
+			return r, true
+		}
+	}
+	return recipe{}, false
+}
+
+// creativeItemFor finds the creative item network ID of the item passed. inv.mu must be held.
+func (inv *inventory) creativeItemFor(item protocol.ItemStack) (uint32, bool) {
+	for _, c := range inv.creativeItems {
+		if sameItem(c.Item, item) {
+			return c.CreativeItemNetworkID, true
+		}
+	}
+	return 0, false
+}
+
+// restore restores the slots passed to the items they held before the client changed them. inv.mu must be held.
+func (inv *inventory) restore(slots []pendingSlot) {
+	for i := len(slots) - 1; i >= 0; i-- {
+		s := slots[i]
+		inv.slots(s.window)[s.slot] = s.old
+	}
+}
+
+// respond handles the responses to item stack requests sent earlier. The stack network IDs of items changed by
+// accepted requests are updated, and the slots changed by rejected requests are restored and sent to the client.
+func (inv *inventory) respond(m *latestmappings.Mappings, responses []protocol.ItemStackResponse) []packet.Packet {
+	inv.mu.Lock()
+	defer inv.mu.Unlock()
+
+	var pks []packet.Packet
+	for _, resp := range responses {
+		changed, ok := inv.pending[resp.RequestID]
+		if !ok {
+			continue
+		}
+		delete(inv.pending, resp.RequestID)
+
+		if resp.Status != protocol.ItemStackResponseStatusOK {
+			inv.restore(changed)
+			for _, s := range changed {
+				pks = append(pks, &legacypacket.InventorySlot{
+					WindowID: s.window,
+					Slot:     s.slot,
+					NewItem:  downgradeItem(m, inv.slots(s.window)[s.slot].Stack),
+				})
+			}
+			continue
+		}
+		for _, container := range resp.ContainerInfo {
+			for _, info := range container.SlotInfo {
+				for _, s := range changed {
+					if s.container != container.Container.ContainerID || s.requestSlot != info.Slot {
+						continue
+					}
+					item := inv.slots(s.window)[s.slot]
+					item.StackNetworkID = info.StackNetworkID
+					item.Stack.Count = uint16(info.Count)
+					if info.Count == 0 {
+						item = protocol.ItemInstance{}
+					}
+					inv.slots(s.window)[s.slot] = item
+				}
+			}
+		}
+	}
+	return pks
+}
+
+// original upgrades a legacy item sent by the client for the window and slot passed. If the item was downgraded from
+// an item sent by the server, that item is returned as is, so that items replaced by a similar item when downgrading
+// are not turned into that similar item. If the slot does not hold the item, the original item is unknown and the
+// legacy item is upgraded as is.
+func (inv *inventory) original(m *latestmappings.Mappings, window, slot uint32, item legacyprotocol.ItemStack) protocol.ItemStack {
+	inv.mu.Lock()
+	defer inv.mu.Unlock()
+	s, _ := inv.upgrade(m, window, slot, item)
+	return s
+}
+
+// upgrade upgrades a legacy item sent by the client for the window and slot passed, like original. False is returned
+// if the slot did not hold the item. inv.mu must be held.
+func (inv *inventory) upgrade(m *latestmappings.Mappings, window, slot uint32, item legacyprotocol.ItemStack) (protocol.ItemStack, bool) {
+	if item.NetworkID == 0 || item.Count <= 0 {
+		return protocol.ItemStack{}, true
+	}
+	if current, ok := inv.windows[window][slot]; ok && downgradesTo(m, current.Stack, item) {
+		return withCount(current.Stack, item.Count), true
+	}
+	return upgradeItem(m, item), false
+}
+
+// originalActions upgrades the old and new items of the inventory actions passed, like original. Items in slots
+// that are not tracked, such as the creative inventory or the crafting result, are upgraded as is. New items that
+// the slot did not yet hold must have been moved from one of the other slots of the transaction, so they are looked
+// up in the old items of the actions. As the transaction is sent as is, the tracked slots are then changed to hold
+// the new items.
+func (inv *inventory) originalActions(m *latestmappings.Mappings, actions []legacyprotocol.InventoryAction) (oldItems, newItems []protocol.ItemStack) {
+	inv.mu.Lock()
+	defer inv.mu.Unlock()
+	oldItems, newItems = inv.upgradeActions(m, actions)
+	for i, action := range actions {
+		window, slot, tracked := inv.trackedSlot(action)
+		if !tracked {
+			continue
+		}
+		networkID := int32(0)
+		if current := inv.slots(window)[slot]; sameItem(current.Stack, newItems[i]) {
+			networkID = current.StackNetworkID
+		}
+		inv.slots(window)[slot] = protocol.ItemInstance{StackNetworkID: networkID, Stack: newItems[i]}
+	}
+	return oldItems, newItems
+}
+
+// upgradeActions upgrades the old and new items of the inventory actions passed, like originalActions, without
+// changing the tracked slots. inv.mu must be held.
+func (inv *inventory) upgradeActions(m *latestmappings.Mappings, actions []legacyprotocol.InventoryAction) (oldItems, newItems []protocol.ItemStack) {
+	oldItems, newItems = make([]protocol.ItemStack, len(actions)), make([]protocol.ItemStack, len(actions))
+	for i, action := range actions {
+		window, slot, tracked := inv.trackedSlot(action)
+		if !tracked {
+			oldItems[i] = upgradeItem(m, action.OldItem)
+			continue
+		}
+		oldItems[i], _ = inv.upgrade(m, window, slot, action.OldItem)
+	}
+	for i, action := range actions {
+		if window, slot, tracked := inv.trackedSlot(action); tracked {
+			var ok bool
+			if newItems[i], ok = inv.upgrade(m, window, slot, action.NewItem); ok {
+				continue
+			}
+		} else if action.NewItem.NetworkID == 0 || action.NewItem.Count <= 0 {
+			continue
+		}
+		newItems[i] = upgradeItem(m, action.NewItem)
+		for _, s := range oldItems {
+			if downgradesTo(m, s, action.NewItem) {
+				newItems[i] = withCount(s, action.NewItem.Count)
+				break
+			}
+		}
+	}
+	return oldItems, newItems
+}
+
+// trackedSlot returns the window and slot under which the items of the slot changed by the action passed are
+// tracked. False is returned if the slot is not tracked, such as the slots of the creative inventory.
+func (inv *inventory) trackedSlot(action legacyprotocol.InventoryAction) (uint32, uint32, bool) {
+	switch action.SourceType {
+	case legacyprotocol.InventoryActionSourceContainer:
+		if action.WindowID < 0 || (action.WindowID == legacyprotocol.WindowIDUI && action.InventorySlot == createdOutputSlot) {
+			return 0, 0, false
+		}
+		return uint32(action.WindowID), action.InventorySlot, true
+	case legacyprotocol.InventoryActionSourceTODO:
+		if action.WindowID != windowCraftingAddIngredient && action.WindowID != windowCraftingRemoveIngredient {
+			return 0, 0, false
+		}
+		_, slot, _ := inv.containerOf(action.WindowID, action.InventorySlot)
+		return legacyprotocol.WindowIDUI, uint32(slot), true
+	}
+	return 0, 0, false
+}
+
+// downgradesTo checks if the item stack passed is downgraded to the same item type as the legacy item passed.
+func downgradesTo(m *latestmappings.Mappings, s protocol.ItemStack, item legacyprotocol.ItemStack) bool {
+	if emptyItem(s) {
+		return false
+	}
+	d := downgradeItem(m, s)
+	return d.NetworkID == item.NetworkID && d.MetadataValue == item.MetadataValue
+}
+
+// withCount returns the item stack passed with its count set to the count passed.
+func withCount(s protocol.ItemStack, count int16) protocol.ItemStack {
+	s.Count = uint16(count)
+	return s
+}
+
+// emptyItem checks if the item stack passed is empty, such as air.
+func emptyItem(s protocol.ItemStack) bool {
+	return s.NetworkID == 0 || s.Count == 0
+}
+
+// sameItem checks if the two item stacks passed are of the same item type.
+func sameItem(a, b protocol.ItemStack) bool {
+	return a.NetworkID == b.NetworkID && a.MetadataValue == b.MetadataValue
+}
@@ -0,0 +1,249 @@
+package latestmappings
+
+import (
+	"bytes"
+	"encoding/binary"
+	"github.com/df-mc/worldupgrader/blockupgrader"
+	"github.com/didntpot/tedac/tedac/legacychunk"
+	"github.com/sandertv/gophertunnel/minecraft/protocol"
+	"github.com/segmentio/fasthash/fnv1"
+	"hash/fnv"
+	"slices"
+	"sort"
+)
+
+// Mappings holds the block states and items of a server, which may include custom blocks, along with their runtime
+// IDs. Servers may register different custom blocks, so every connection should use the Mappings of the server it is
+// connected to. Mappings are not modified after they are created, so they may be shared freely.
+type Mappings struct {
+	stateRuntimeIDs  map[StateHash]uint32
+	runtimeIDToState map[uint32]blockupgrader.BlockState
+	customBlocks     map[string]struct{}
+	airRuntimeID     uint32
+
+	itemRuntimeIDsToNames map[int32]string
+	itemNamesToRuntimeIDs map[string]int32
+}
+
+// New returns the Mappings of a server that registered the custom blocks and items passed, which it sends in the
+// StartGame packet. If hashes is true, the runtime IDs of blocks are the hashes of their states rather than their
+// index. The items sent by the server hold the runtime IDs of all items, including custom items. If none are passed,
+// the vanilla items embedded in the package are used instead, which may be outdated. Servers without custom blocks
+// or items share the same vanilla Mappings, rather than building a copy for every connection.
+func New(customBlocks []protocol.BlockEntry, hashes bool, items []protocol.ItemEntry) *Mappings {
+	if vanilla != nil && len(customBlocks) == 0 && !hashes && vanillaItems(items) {
+		return vanilla
+	}
+	custom := customStates(customBlocks)
+	adjustedStates := append(slices.Clone(states), custom...)
+	sort.SliceStable(adjustedStates, func(i, j int) bool {
+		stateOne, stateTwo := adjustedStates[i], adjustedStates[j]
+		if stateOne.Name == stateTwo.Name {
+			return false
+		}
+		return fnv1.HashString64(stateOne.Name) < fnv1.HashString64(stateTwo.Name)
+	})
+
+	m := &Mappings{
+		stateRuntimeIDs:       make(map[StateHash]uint32, len(adjustedStates)),
+		runtimeIDToState:      make(map[uint32]blockupgrader.BlockState, len(adjustedStates)),
+		customBlocks:          make(map[string]struct{}),
+		itemRuntimeIDsToNames: make(map[int32]string, max(len(items), len(itemRuntimeIDs))),
+		itemNamesToRuntimeIDs: make(map[string]int32, max(len(items), len(itemRuntimeIDs))),
+	}
+	for _, state := range custom {
+		m.customBlocks[state.Name] = struct{}{}
+	}
+	for i, state := range adjustedStates {
+		rid := uint32(i)
+		if hashes {
+			rid = networkBlockHash(state)
+		}
+		m.stateRuntimeIDs[HashState(state)] = rid
+		m.runtimeIDToState[rid] = state
+	}
+	m.airRuntimeID, _ = m.StateToRuntimeID("minecraft:air", nil)
+
+	if len(items) == 0 {
+		for name, rid := range itemRuntimeIDs {
+			m.itemNamesToRuntimeIDs[name] = rid
+			m.itemRuntimeIDsToNames[rid] = name
+		}
+		return m
+	}
+	for _, item := range items {
+		m.itemNamesToRuntimeIDs[item.Name] = int32(item.RuntimeID)
+		m.itemRuntimeIDsToNames[int32(item.RuntimeID)] = item.Name
+	}
+	return m
+}
+
+// vanillaItems checks if the items passed are the same as the vanilla items embedded in the package. An empty list
+// is treated as vanilla, as New then uses the embedded items.
+func vanillaItems(items []protocol.ItemEntry) bool {
+	if len(items) == 0 {
+		return true
+	}
+	if len(items) != len(itemRuntimeIDs) {
+		return false
+	}
+	for _, item := range items {
+		if rid, ok := itemRuntimeIDs[item.Name]; !ok || rid != int32(item.RuntimeID) {
+			return false
+		}
+	}
+	return true
+}
+
+// StateToRuntimeID converts a name and its state properties to a runtime ID.
+func (m *Mappings) StateToRuntimeID(name string, properties map[string]any) (runtimeID uint32, found bool) {
+	state := blockupgrader.BlockState{Name: name, Properties: properties}
+	if _, ok := m.customBlocks[name]; !ok {
+		// Custom blocks are never upgraded, as the block upgrader does not know about them.
+		state.Version = legacychunk.CurrentBlockVersion
+		state = blockupgrader.Upgrade(state)
+	}
+	rid, ok := m.stateRuntimeIDs[HashState(state)]
+	return rid, ok
+}
+
+// RuntimeIDToState converts a runtime ID to a name and its state properties.
+func (m *Mappings) RuntimeIDToState(runtimeID uint32) (name string, properties map[string]any, found bool) {
+	s, ok := m.runtimeIDToState[runtimeID]
+	return s.Name, s.Properties, ok
+}
+
+// AirRuntimeID returns the runtime ID of air.
+func (m *Mappings) AirRuntimeID() uint32 {
+	return m.airRuntimeID
+}
+
+// CustomBlock checks if the block with the name passed is a custom block of the server.
+func (m *Mappings) CustomBlock(name string) bool {
+	_, ok := m.customBlocks[name]
+	return ok
+}
+
+// ItemRuntimeIDToName converts an item runtime ID to a string ID.
+func (m *Mappings) ItemRuntimeIDToName(runtimeID int32) (name string, found bool) {
+	name, ok := m.itemRuntimeIDsToNames[runtimeID]
+	return name, ok
+}
+
+// ItemNameToRuntimeID converts a string ID to an item runtime ID.
+func (m *Mappings) ItemNameToRuntimeID(name string) (runtimeID int32, found bool) {
+	rid, ok := m.itemNamesToRuntimeIDs[name]
+	return rid, ok
+}
+
+// customStates returns all block states of the custom blocks passed. Every combination of the values of the
+// properties of a block is a separate state.
+func customStates(entries []protocol.BlockEntry) []blockupgrader.BlockState {
+	var customStates []blockupgrader.BlockState
+	for _, entry := range entries {
+		permutations := []map[string]any{{}}
+		properties, _ := entry.Properties["properties"].([]any)
+		for _, p := range properties {
+			property, _ := p.(map[string]any)
+			name, _ := property["name"].(string)
+			values, _ := property["enum"].([]any)
+			if name == "" || len(values) == 0 {
+				continue
+			}
+			next := make([]map[string]any, 0, len(permutations)*len(values))
+			for _, permutation := range permutations {
+				for _, v := range values {
+					if !validProperty(v) {
+						continue
+					}
+					m := make(map[string]any, len(permutation)+1)
+					for k, existing := range permutation {
+						m[k] = existing
+					}
+					m[name] = v
+					next = append(next, m)
+				}
+			}
+			permutations = next
+		}
+		for _, permutation := range permutations {
+			customStates = append(customStates, blockupgrader.BlockState{Name: entry.Name, Properties: permutation})
+		}
+	}
+	return customStates
+}
+
+// validProperty checks if the value passed may be the value of a block property.
+func validProperty(v any) bool {
+	switch v.(type) {
+	case bool, uint8, int32, string:
+		return true
+	}
+	return false
+}
+
+// networkBlockHash returns the hash of the block state passed that is used as its runtime ID by servers that enable
+// block network ID hashes. It is the FNV-1a hash of the state encoded as little endian NBT, of which the tags are
+// sorted by name.
+func networkBlockHash(state blockupgrader.BlockState) uint32 {
+	if state.Name == "minecraft:unknown" {
+		return 0xfffffffe
+	}
+	buf := bytes.NewBuffer(nil)
+	writeTagHeader(buf, tagCompound, "")
+	writeTagHeader(buf, tagString, "name")
+	writeString(buf, state.Name)
+	writeTagHeader(buf, tagCompound, "states")
+
+	keys := make([]string, 0, len(state.Properties))
+	for k := range state.Properties {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		switch v := state.Properties[k].(type) {
+		case bool:
+			writeTagHeader(buf, tagByte, k)
+			if v {
+				buf.WriteByte(1)
+			} else {
+				buf.WriteByte(0)
+			}
+		case uint8:
+			writeTagHeader(buf, tagByte, k)
+			buf.WriteByte(v)
+		case int32:
+			writeTagHeader(buf, tagInt, k)
+			_ = binary.Write(buf, binary.LittleEndian, v)
+		case string:
+			writeTagHeader(buf, tagString, k)
+			writeString(buf, v)
+		}
+	}
+	buf.WriteByte(tagEnd)
+	buf.WriteByte(tagEnd)
+
+	h := fnv.New32a()
+	_, _ = h.Write(buf.Bytes())
+	return h.Sum32()
+}
+
+const (
+	tagEnd      = 0
+	tagByte     = 1
+	tagInt      = 3
+	tagString   = 8
+	tagCompound = 10
+)
+
+// writeTagHeader writes the type and name of an NBT tag to the buffer passed.
+func writeTagHeader(buf *bytes.Buffer, tagType byte, name string) {
+	buf.WriteByte(tagType)
+	writeString(buf, name)
+}
+
+// writeString writes a string prefixed by its length as a little endian NBT string to the buffer passed.
+func writeString(buf *bytes.Buffer, s string) {
+	_ = binary.Write(buf, binary.LittleEndian, uint16(len(s)))
+	buf.WriteString(s)
+}
@@ -0,0 +1,34 @@
+package latestmappings
+
+import (
+	"github.com/sandertv/gophertunnel/minecraft/protocol"
+	"testing"
+)
+
+func TestNewVanilla(t *testing.T) {
+	items := make([]protocol.ItemEntry, 0, len(itemRuntimeIDs))
+	for name, rid := range itemRuntimeIDs {
+		items = append(items, protocol.ItemEntry{Name: name, RuntimeID: int16(rid)})
+	}
+	tests := []struct {
+		name         string
+		customBlocks []protocol.BlockEntry
+		hashes       bool
+		items        []protocol.ItemEntry
+		want         bool
+	}{
+		{name: "no items", want: true},
+		{name: "vanilla items", items: items, want: true},
+		{name: "hashes", hashes: true, want: false},
+		{name: "custom block", customBlocks: []protocol.BlockEntry{{Name: "tedac:custom"}}, want: false},
+		{name: "custom item", items: append(items[:len(items):len(items)], protocol.ItemEntry{Name: "tedac:custom", RuntimeID: 10000}), want: false},
+		{name: "moved item", items: []protocol.ItemEntry{{Name: items[0].Name, RuntimeID: items[0].RuntimeID + 1}}, want: false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := New(test.customBlocks, test.hashes, test.items) == Vanilla(); got != test.want {
+				t.Errorf("New returned vanilla mappings: %v, want %v", got, test.want)
+			}
+		})
+	}
+}
@@ -4,9 +4,7 @@ import (
 	"bytes"
 	"fmt"
 	"github.com/df-mc/worldupgrader/blockupgrader"
-	"github.com/didntpot/tedac/tedac/legacychunk"
 	"github.com/sandertv/gophertunnel/minecraft/nbt"
-	"github.com/segmentio/fasthash/fnv1"
 	"sort"
 	"strings"
 	"unsafe"
@@ -20,31 +18,24 @@ var (
 
 	// states holds a list of all possible vanilla block states.
 	states []blockupgrader.BlockState
-	// stateRuntimeIDs holds a map for looking up the runtime ID of a block by the stateHash it produces.
-	stateRuntimeIDs = map[StateHash]uint32{}
-	// runtimeIDToState holds a map for looking up the blockState of a block by its runtime ID.
-	runtimeIDToState = map[uint32]blockupgrader.BlockState{}
 )
 
 var (
 	//go:embed item_runtime_ids.nbt
 	itemRuntimeIDData []byte
-	// itemRuntimeIDsToNames holds a map to translate item runtime IDs to string IDs.
-	itemRuntimeIDsToNames = map[int32]string{}
-	// itemNamesToRuntimeIDs holds a map to translate item string IDs to runtime IDs.
-	itemNamesToRuntimeIDs = map[string]int32{}
+	// itemRuntimeIDs holds the runtime IDs of all vanilla items, keyed by their string IDs. They are only used if a
+	// server does not send its items.
+	itemRuntimeIDs map[string]int32
 )
 
+// vanilla holds the Mappings of servers without any custom blocks.
+var vanilla *Mappings
+
 // init initializes the item and state mappings.
 func init() {
-	var items map[string]int32
-	if err := nbt.Unmarshal(itemRuntimeIDData, &items); err != nil {
+	if err := nbt.Unmarshal(itemRuntimeIDData, &itemRuntimeIDs); err != nil {
 		panic(err)
 	}
-	for name, rid := range items {
-		itemNamesToRuntimeIDs[name] = rid
-		itemRuntimeIDsToNames[rid] = name
-	}
 
 	dec := nbt.NewDecoder(bytes.NewBuffer(blockStateData))
 
@@ -55,60 +46,25 @@ func init() {
 		if err := dec.Decode(&s); err != nil {
 			break
 		}
-
-		rid := uint32(len(states))
 		states = append(states, s)
-
-		stateRuntimeIDs[HashState(s)] = rid
-		runtimeIDToState[rid] = s
 	}
+	vanilla = New(nil, false, nil)
 }
 
-// Adjust adjusts the latest mappings to account for custom states.
-func Adjust(customStates []blockupgrader.BlockState) {
-	adjustedStates := append(states, customStates...)
-	sort.SliceStable(adjustedStates, func(i, j int) bool {
-		stateOne, stateTwo := adjustedStates[i], adjustedStates[j]
-		if stateOne.Name == stateTwo.Name {
-			return false
-		}
-		return fnv1.HashString64(stateOne.Name) < fnv1.HashString64(stateTwo.Name)
-	})
-
-	stateRuntimeIDs = make(map[StateHash]uint32, len(adjustedStates))
-	runtimeIDToState = make(map[uint32]blockupgrader.BlockState, len(adjustedStates))
-	for rid, state := range adjustedStates {
-		stateRuntimeIDs[HashState(state)] = uint32(rid)
-		runtimeIDToState[uint32(rid)] = state
-	}
+// Vanilla returns the Mappings of servers without any custom blocks. It should only be used if the Mappings of the
+// server are not known.
+func Vanilla() *Mappings {
+	return vanilla
 }
 
-// StateToRuntimeID converts a name and its state properties to a runtime ID.
+// StateToRuntimeID converts a name and its state properties to a vanilla runtime ID.
 func StateToRuntimeID(name string, properties map[string]any) (runtimeID uint32, found bool) {
-	rid, ok := stateRuntimeIDs[HashState(blockupgrader.Upgrade(blockupgrader.BlockState{
-		Name:       name,
-		Properties: properties,
-		Version:    legacychunk.CurrentBlockVersion,
-	}))]
-	return rid, ok
+	return vanilla.StateToRuntimeID(name, properties)
 }
 
-// RuntimeIDToState converts a runtime ID to a name and its state properties.
+// RuntimeIDToState converts a vanilla runtime ID to a name and its state properties.
 func RuntimeIDToState(runtimeID uint32) (name string, properties map[string]any, found bool) {
-	s := runtimeIDToState[runtimeID]
-	return s.Name, s.Properties, true
-}
-
-// ItemRuntimeIDToName converts an item runtime ID to a string ID.
-func ItemRuntimeIDToName(runtimeID int32) (name string, found bool) {
-	name, ok := itemRuntimeIDsToNames[runtimeID]
-	return name, ok
-}
-
-// ItemNameToRuntimeID converts a string ID to an item runtime ID.
-func ItemNameToRuntimeID(name string) (runtimeID int32, found bool) {
-	rid, ok := itemNamesToRuntimeIDs[name]
-	return rid, ok
+	return vanilla.RuntimeIDToState(runtimeID)
 }
 
 // StateHash is a struct that may be used as a map key for block states. It contains the name of the block state
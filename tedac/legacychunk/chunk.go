@@ -1,27 +1,38 @@
 package legacychunk
 
 import (
+	"github.com/df-mc/dragonfly/server/block/cube"
 	"sync"
 )
 
-// TODO: Multiple dimensions.
-const (
-	maxY = 255
-	minY = 0
+var (
+	// OverworldRange is the vertical range of the Overworld in v1.12.0.
+	OverworldRange = cube.Range{0, 255}
+	// NetherRange is the vertical range of the Nether in v1.12.0. Chunks in the Nether are only 128 blocks high.
+	NetherRange = cube.Range{0, 127}
+	// EndRange is the vertical range of the End in v1.12.0.
+	EndRange = cube.Range{0, 255}
 )
 
-const (
-	maxSubChunkIndex = (maxY >> 4) - minSubChunkY
-
-	minSubChunkY  = minY >> 4
-	subChunkCount = maxSubChunkIndex + 1
-)
+// DimensionRange returns the vertical range of the dimension with the ID passed in v1.12.0. Unknown dimensions are
+// treated as the Overworld.
+func DimensionRange(dimension int32) cube.Range {
+	switch dimension {
+	case 1:
+		return NetherRange
+	case 2:
+		return EndRange
+	}
+	return OverworldRange
+}
 
 // Chunk is a segment in the world with a size of 16x16x256 blocks. A chunk contains multiple sub chunks
 // and stores other information such as biomes.
 // It is not safe to call methods on Chunk simultaneously from multiple goroutines.
 type Chunk struct {
 	sync.Mutex
+	// r holds the (vertical) range of the Chunk. It includes both the minimum and maximum coordinates.
+	r cube.Range
 	// air is the runtime ID of air.
 	air uint32
 	// sub holds all sub chunks part of the chunk. The pointers held by the array are nil if no sub chunk is
@@ -31,13 +42,19 @@ type Chunk struct {
 	biomes [256]uint8
 }
 
-// New initialises a new chunk and returns it, so that it may be used.
-func New(air uint32) *Chunk {
-	sub := make([]*SubChunk, subChunkCount)
-	for i := 0; i < subChunkCount; i++ {
+// New initialises a new chunk with the range passed and returns it, so that it may be used.
+func New(air uint32, r cube.Range) *Chunk {
+	n := (r.Height() >> 4) + 1
+	sub := make([]*SubChunk, n)
+	for i := 0; i < n; i++ {
 		sub[i] = NewSubChunk(air)
 	}
-	return &Chunk{air: air, sub: sub}
+	return &Chunk{r: r, air: air, sub: sub}
+}
+
+// Range returns the cube.Range of the Chunk as passed to New.
+func (chunk *Chunk) Range() cube.Range {
+	return chunk.r
 }
 
 // Sub returns a list of all sub chunks present in the chunk.
@@ -68,7 +85,7 @@ func (chunk *Chunk) Block(x uint8, y int16, z uint8, layer uint8) uint32 {
 // SetBlock sets the runtime ID of a block at a given x, y and z in a chunk at the given layer. If no
 // SubChunk exists at the given y, a new SubChunk is created and the block is set.
 func (chunk *Chunk) SetBlock(x uint8, y int16, z uint8, layer uint8, runtimeID uint32) {
-	sub := chunk.sub[subIndex(y)]
+	sub := chunk.sub[chunk.subIndex(y)]
 	if uint8(len(sub.storages)) <= layer && runtimeID == chunk.air {
 		// Air was set at n layer, but there were less than n layers, so there already was air there.
 		// Don't do anything with this, just return.
@@ -80,16 +97,16 @@ func (chunk *Chunk) SetBlock(x uint8, y int16, z uint8, layer uint8, runtimeID u
 // HighestBlock iterates from the highest non-empty sub chunk downwards to find the Y value of the highest
 // non-air block at an x and z. If no blocks are present in the column, 0 is returned.
 func (chunk *Chunk) HighestBlock(x, z uint8) int16 {
-	for index := int16(maxSubChunkIndex); index >= 0; index-- {
+	for index := int16(len(chunk.sub) - 1); index >= 0; index-- {
 		if sub := chunk.sub[index]; !sub.Empty() {
 			for y := 15; y >= 0; y-- {
 				if rid := sub.storages[0].RuntimeID(x, uint8(y), z); rid != chunk.air {
-					return int16(y) | subY(index)
+					return int16(y) | chunk.subY(index)
 				}
 			}
 		}
 	}
-	return minY
+	return int16(chunk.r[0])
 }
 
 // Compact compacts the chunk as much as possible, getting rid of any sub chunks that are empty, and compacts
@@ -103,7 +120,7 @@ func (chunk *Chunk) Compact() {
 
 // subChunk finds the correct SubChunk in the Chunk by a Y value.
 func (chunk *Chunk) subChunk(y int16) *SubChunk {
-	return chunk.sub[subIndex(y)]
+	return chunk.sub[chunk.subIndex(y)]
 }
 
 // columnOffset returns the offset in a byte slice that the column at a specific x and z may be found.
@@ -112,11 +129,11 @@ func columnOffset(x, z uint8) uint8 {
 }
 
 // subIndex returns the sub chunk Y index matching the y value passed.
-func subIndex(y int16) int16 {
-	return (y >> 4) - minSubChunkY
+func (chunk *Chunk) subIndex(y int16) int16 {
+	return (y - int16(chunk.r[0])) >> 4
 }
 
 // subY returns the sub chunk Y value matching the index passed.
-func subY(index int16) int16 {
-	return (index + minSubChunkY) << 4
+func (chunk *Chunk) subY(index int16) int16 {
+	return (index << 4) + int16(chunk.r[0])
 }
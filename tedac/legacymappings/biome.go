@@ -0,0 +1,35 @@
+package legacymappings
+
+import (
+	_ "embed"
+	"encoding/json"
+)
+
+var (
+	//go:embed biome_id_map.json
+	biomeIDData []byte
+
+	// biomeIDs maps the ID of every biome in the latest version to the ID of the same or the most similar biome in
+	// v1.12.0.
+	biomeIDs = map[uint32]uint8{}
+)
+
+// defaultBiomeID is the ID of the biome used for biomes unknown to the mapping, such as custom biomes. It is the
+// ID of the plains biome.
+const defaultBiomeID = 1
+
+// init reads all biome IDs from the resource JSON.
+func init() {
+	if err := json.Unmarshal(biomeIDData, &biomeIDs); err != nil {
+		panic(err)
+	}
+}
+
+// BiomeID returns the ID of the biome in v1.12.0 closest to the biome with the ID passed from the latest version.
+// Biomes added after v1.12.0 are replaced by a biome with similar grass and water colours.
+func BiomeID(id uint32) uint8 {
+	if legacyID, ok := biomeIDs[id]; ok {
+		return legacyID
+	}
+	return defaultBiomeID
+}
@@ -0,0 +1,27 @@
+package legacymappings
+
+import "testing"
+
+func TestBiomeID(t *testing.T) {
+	tests := []struct {
+		name string
+		id   uint32
+		want uint8
+	}{
+		{name: "ocean", id: 0, want: 0},
+		{name: "plains", id: 1, want: 1},
+		{name: "jungle", id: 21, want: 21},
+		{name: "bamboo jungle", id: 168, want: 21},
+		{name: "bamboo jungle hills", id: 169, want: 22},
+		{name: "nether wastes", id: 8, want: 8},
+		{name: "crimson forest", id: 179, want: 8},
+		{name: "custom", id: 100000, want: defaultBiomeID},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := BiomeID(test.id); got != test.want {
+				t.Errorf("BiomeID(%v) = %v, want %v", test.id, got, test.want)
+			}
+		})
+	}
+}
@@ -24,6 +24,8 @@ var (
 	stateToRuntimeID = map[latestmappings.StateHash]uint32{}
 	// runtimeIDToState maps a runtime ID to a state.
 	runtimeIDToState = map[uint32]blockupgrader.BlockState{}
+	// nameToRuntimeID maps the name of a block in the latest version to the runtime ID of its first state.
+	nameToRuntimeID = map[string]uint32{}
 )
 
 // init reads all block entries from the resource JSON, and sets the according values in the maps.
@@ -60,23 +62,51 @@ func init() {
 				Data:     int16(meta),
 				LegacyID: legacyId,
 			})
-			stateToRuntimeID[latestmappings.HashState(latestBlockState)] = legacyRID
+			hash := latestmappings.HashState(latestBlockState)
+			if _, ok := stateToRuntimeID[hash]; !ok {
+				// Several metas may upgrade to the same state, in which case the lowest one is the valid variant.
+				stateToRuntimeID[hash] = legacyRID
+			}
 			runtimeIDToState[legacyRID] = latestBlockState
+			if _, ok := nameToRuntimeID[latestBlockState.Name]; !ok {
+				nameToRuntimeID[latestBlockState.Name] = legacyRID
+			}
 		}
 	}
 }
 
-// StateToRuntimeID converts a name and its state properties to a runtime ID.
+// StateToRuntimeID converts a name and its state properties to a runtime ID. Blocks that did not exist in v1.12.0
+// are replaced by a similar block, or by an update block if there is none.
 func StateToRuntimeID(name string, properties map[string]any) uint32 {
-	rid, ok := stateToRuntimeID[latestmappings.HashState(blockupgrader.Upgrade(blockupgrader.BlockState{
+	if rid, ok := stateToRuntimeID[hashState(name, properties)]; ok {
+		return rid
+	}
+	exists := func(name string) bool {
+		_, ok := nameToRuntimeID[name]
+		return ok
+	}
+	if !exists(name) {
+		sub, ok := substituteBlock(name, exists)
+		if !ok {
+			return stateToRuntimeID[latestmappings.HashState(blockupgrader.BlockState{Name: "minecraft:info_update"})]
+		}
+		if rid, ok := stateToRuntimeID[hashState(sub, properties)]; ok {
+			// The substitute has the same properties, such as the direction of stairs, so we can keep them.
+			return rid
+		}
+		name = sub
+	}
+	// The block existed, but not with these properties, so we use its default state instead.
+	return nameToRuntimeID[name]
+}
+
+// hashState upgrades the block state with the name and properties passed and returns its hash.
+func hashState(name string, properties map[string]any) latestmappings.StateHash {
+	return latestmappings.HashState(blockupgrader.Upgrade(blockupgrader.BlockState{
 		Name:       name,
 		Properties: properties,
 		Version:    legacychunk.LegacyBlockVersion,
-	}))]
-	if !ok {
-		rid = stateToRuntimeID[latestmappings.HashState(blockupgrader.BlockState{Name: "minecraft:info_update"})]
-	}
-	return rid
+	}))
 }
 
 // RuntimeIDToState converts a runtime ID to a name and its state properties.
@@ -0,0 +1,162 @@
+package legacymappings
+
+// entitySubstitute is an entity type that existed in v1.12.0, shown instead of an entity type added later on. The
+// entity is scaled by the scale held, so that it roughly matches the size of the entity it replaces.
+type entitySubstitute struct {
+	entityType string
+	scale      float32
+}
+
+var (
+	// entityTypes holds the identifiers of all entity types that exist in v1.12.0.
+	entityTypes = map[string]struct{}{
+		"minecraft:agent":                  {},
+		"minecraft:area_effect_cloud":      {},
+		"minecraft:armor_stand":            {},
+		"minecraft:arrow":                  {},
+		"minecraft:balloon":                {},
+		"minecraft:bat":                    {},
+		"minecraft:blaze":                  {},
+		"minecraft:boat":                   {},
+		"minecraft:cat":                    {},
+		"minecraft:cave_spider":            {},
+		"minecraft:chalkboard":             {},
+		"minecraft:chest_minecart":         {},
+		"minecraft:chicken":                {},
+		"minecraft:cod":                    {},
+		"minecraft:command_block_minecart": {},
+		"minecraft:cow":                    {},
+		"minecraft:creeper":                {},
+		"minecraft:dolphin":                {},
+		"minecraft:donkey":                 {},
+		"minecraft:dragon_fireball":        {},
+		"minecraft:drowned":                {},
+		"minecraft:egg":                    {},
+		"minecraft:elder_guardian":         {},
+		"minecraft:ender_crystal":          {},
+		"minecraft:ender_dragon":           {},
+		"minecraft:ender_pearl":            {},
+		"minecraft:enderman":               {},
+		"minecraft:endermite":              {},
+		"minecraft:evocation_fang":         {},
+		"minecraft:evocation_illager":      {},
+		"minecraft:eye_of_ender_signal":    {},
+		"minecraft:falling_block":          {},
+		"minecraft:fireball":               {},
+		"minecraft:fireworks_rocket":       {},
+		"minecraft:fishing_hook":           {},
+		"minecraft:ghast":                  {},
+		"minecraft:guardian":               {},
+		"minecraft:hopper_minecart":        {},
+		"minecraft:horse":                  {},
+		"minecraft:husk":                   {},
+		"minecraft:ice_bomb":               {},
+		"minecraft:iron_golem":             {},
+		"minecraft:item":                   {},
+		"minecraft:leash_knot":             {},
+		"minecraft:lightning_bolt":         {},
+		"minecraft:lingering_potion":       {},
+		"minecraft:llama":                  {},
+		"minecraft:llama_spit":             {},
+		"minecraft:magma_cube":             {},
+		"minecraft:minecart":               {},
+		"minecraft:mooshroom":              {},
+		"minecraft:moving_block":           {},
+		"minecraft:mule":                   {},
+		"minecraft:npc":                    {},
+		"minecraft:ocelot":                 {},
+		"minecraft:painting":               {},
+		"minecraft:panda":                  {},
+		"minecraft:parrot":                 {},
+		"minecraft:phantom":                {},
+		"minecraft:pig":                    {},
+		"minecraft:pillager":               {},
+		"minecraft:player":                 {},
+		"minecraft:polar_bear":             {},
+		"minecraft:pufferfish":             {},
+		"minecraft:rabbit":                 {},
+		"minecraft:ravager":                {},
+		"minecraft:salmon":                 {},
+		"minecraft:sheep":                  {},
+		"minecraft:shulker":                {},
+		"minecraft:shulker_bullet":         {},
+		"minecraft:silverfish":             {},
+		"minecraft:skeleton":               {},
+		"minecraft:skeleton_horse":         {},
+		"minecraft:slime":                  {},
+		"minecraft:small_fireball":         {},
+		"minecraft:snow_golem":             {},
+		"minecraft:snowball":               {},
+		"minecraft:spider":                 {},
+		"minecraft:splash_potion":          {},
+		"minecraft:squid":                  {},
+		"minecraft:stray":                  {},
+		"minecraft:thrown_trident":         {},
+		"minecraft:tnt":                    {},
+		"minecraft:tnt_minecart":           {},
+		"minecraft:tripod_camera":          {},
+		"minecraft:tropicalfish":           {},
+		"minecraft:turtle":                 {},
+		"minecraft:vex":                    {},
+		"minecraft:villager":               {},
+		"minecraft:villager_v2":            {},
+		"minecraft:vindicator":             {},
+		"minecraft:wandering_trader":       {},
+		"minecraft:witch":                  {},
+		"minecraft:wither":                 {},
+		"minecraft:wither_skeleton":        {},
+		"minecraft:wither_skull":           {},
+		"minecraft:wither_skull_dangerous": {},
+		"minecraft:wolf":                   {},
+		"minecraft:xp_bottle":              {},
+		"minecraft:xp_orb":                 {},
+		"minecraft:zombie":                 {},
+		"minecraft:zombie_horse":           {},
+		"minecraft:zombie_pigman":          {},
+		"minecraft:zombie_villager":        {},
+		"minecraft:zombie_villager_v2":     {},
+	}
+	// entitySubstitutes maps the identifiers of entity types added after v1.12.0 to a similar entity type that
+	// existed in v1.12.0. Entity types not present here and in entityTypes have no sensible stand-in.
+	entitySubstitutes = map[string]entitySubstitute{
+		"minecraft:allay":                         {"minecraft:vex", 1},
+		"minecraft:armadillo":                     {"minecraft:rabbit", 1},
+		"minecraft:axolotl":                       {"minecraft:salmon", 1},
+		"minecraft:bee":                           {"minecraft:bat", 1},
+		"minecraft:bogged":                        {"minecraft:stray", 1},
+		"minecraft:breeze":                        {"minecraft:blaze", 1},
+		"minecraft:breeze_wind_charge_projectile": {"minecraft:snowball", 1},
+		"minecraft:camel":                         {"minecraft:llama", 1.25},
+		"minecraft:chest_boat":                    {"minecraft:boat", 1},
+		"minecraft:creaking":                      {"minecraft:zombie", 1.1},
+		"minecraft:fox":                           {"minecraft:wolf", 0.8},
+		"minecraft:frog":                          {"minecraft:rabbit", 1},
+		"minecraft:glow_squid":                    {"minecraft:squid", 1},
+		"minecraft:goat":                          {"minecraft:sheep", 1},
+		"minecraft:hoglin":                        {"minecraft:pig", 1.5},
+		"minecraft:piglin":                        {"minecraft:zombie_pigman", 1},
+		"minecraft:piglin_brute":                  {"minecraft:zombie_pigman", 1},
+		"minecraft:sniffer":                       {"minecraft:cow", 1.5},
+		"minecraft:strider":                       {"minecraft:magma_cube", 1},
+		"minecraft:tadpole":                       {"minecraft:cod", 0.5},
+		"minecraft:trader_llama":                  {"minecraft:llama", 1},
+		"minecraft:warden":                        {"minecraft:iron_golem", 1.05},
+		"minecraft:wind_charge_projectile":        {"minecraft:snowball", 1},
+		"minecraft:zoglin":                        {"minecraft:pig", 1.5},
+		"minecraft:zombified_piglin":              {"minecraft:zombie_pigman", 1},
+	}
+)
+
+// EntityType returns the identifier of the entity type in v1.12.0 shown for the entity type with the identifier
+// passed. Entity types added after v1.12.0 are replaced by a similar entity type, which should be scaled by the
+// scale returned. False is returned if the entity type has no v1.12.0 equivalent, in which case the entity cannot
+// be shown at all.
+func EntityType(entityType string) (string, float32, bool) {
+	if _, ok := entityTypes[entityType]; ok {
+		return entityType, 1, true
+	}
+	if sub, ok := entitySubstitutes[entityType]; ok {
+		return sub.entityType, sub.scale, true
+	}
+	return "", 0, false
+}
@@ -0,0 +1,27 @@
+package legacymappings
+
+import "testing"
+
+func TestEntityType(t *testing.T) {
+	tests := []struct {
+		entityType string
+		want       string
+		wantScale  float32
+		wantOK     bool
+	}{
+		{entityType: "minecraft:zombie", want: "minecraft:zombie", wantScale: 1, wantOK: true},
+		{entityType: "minecraft:player", want: "minecraft:player", wantScale: 1, wantOK: true},
+		{entityType: "minecraft:zombified_piglin", want: "minecraft:zombie_pigman", wantScale: 1, wantOK: true},
+		{entityType: "minecraft:warden", want: "minecraft:iron_golem", wantScale: 1.05, wantOK: true},
+		{entityType: "minecraft:allay", want: "minecraft:vex", wantScale: 1, wantOK: true},
+		{entityType: "tedac:custom", wantOK: false},
+	}
+	for _, test := range tests {
+		t.Run(test.entityType, func(t *testing.T) {
+			got, scale, ok := EntityType(test.entityType)
+			if got != test.want || scale != test.wantScale || ok != test.wantOK {
+				t.Errorf("EntityType(%v) = (%v, %v, %v), want (%v, %v, %v)", test.entityType, got, scale, ok, test.want, test.wantScale, test.wantOK)
+			}
+		})
+	}
+}
@@ -3,7 +3,10 @@ package legacymappings
 import (
 	_ "embed"
 	"encoding/json"
+	"fmt"
 	"github.com/didntpot/tedac/tedac/latestmappings"
+	"strconv"
+	"strings"
 )
 
 var (
@@ -40,16 +43,60 @@ func ItemNameByID(id int16) (string, bool) {
 	return name, ok
 }
 
-// ItemIDByName returns an item's ID by its name.
+// ItemIDByName returns an item's ID by its name. Items that did not exist in v1.12.0 are replaced by a similar item,
+// or by a name tag if there is none, in which case false is returned.
 func ItemIDByName(name string) (int16, bool) {
+	id, _, ok := ItemByName(name, 0)
+	return id, ok
+}
+
+// ItemByName returns an item's ID and metadata value by its name and metadata value in the latest version. Items
+// that were split up after v1.12.0 are returned as the item they used to be. Items that did not exist in v1.12.0 are
+// replaced by a similar item, or by a name tag if there is none, in which case false is returned.
+func ItemByName(name string, meta int16) (int16, int16, bool) {
+	if v, ok := itemEquivalents[name]; ok {
+		if id, ok := itemNamesToIDs[v.name]; ok {
+			return id, v.meta, true
+		}
+	}
+	if id, ok := itemNamesToIDs[name]; ok {
+		return id, meta, true
+	}
 	if alias, ok := latestmappings.AliasFromUpdatedItemName(name); ok {
+		// Some aliases hold the metadata value of the item, such as minecraft:dye:1.
 		name = alias
+		if i := strings.LastIndexByte(alias, ':'); i > len("minecraft") {
+			if m, err := strconv.ParseInt(alias[i+1:], 10, 16); err == nil {
+				name, meta = alias[:i], int16(m)
+			}
+		}
+	}
+	if id, ok := itemNamesToIDs[name]; ok {
+		return id, meta, true
 	}
-	id, ok := itemNamesToIDs[name]
+	if v, ok := substituteItem(name); ok {
+		return itemNamesToIDs[v.name], v.meta, false
+	}
+	return itemNamesToIDs["minecraft:name_tag"], 0, false
+}
+
+// ItemByID returns an item's name and metadata value in the latest version by its legacy ID and metadata value.
+// Items that were split up after v1.12.0 are returned as the item they were split up into.
+func ItemByID(id, meta int16) (string, int16, bool) {
+	name, ok := itemIDsToNames[id]
 	if !ok {
-		id = itemNamesToIDs["minecraft:name_tag"]
+		return "", 0, false
 	}
-	return id, ok
+	if updated, ok := latestmappings.UpdatedItemNameFromAlias(fmt.Sprintf("%v:%v", name, meta)); ok {
+		return updated, 0, true
+	}
+	if updated, ok := itemVariantsToNames[itemVariant{name: name, meta: meta}]; ok {
+		return updated, 0, true
+	}
+	if alias, ok := latestmappings.UpdatedItemNameFromAlias(name); ok {
+		name = alias
+	}
+	return name, meta, true
 }
 
 // Items returns a slice of all item entries.
@@ -0,0 +1,41 @@
+package legacymappings
+
+import "testing"
+
+func TestItemRoundTrip(t *testing.T) {
+	tests := []struct {
+		name         string
+		wantID       int16
+		wantMeta     int16
+		wantOriginal bool
+		wantName     string
+	}{
+		{name: "minecraft:diamond_sword", wantID: 276, wantMeta: 0, wantOriginal: true, wantName: "minecraft:diamond_sword"},
+		{name: "minecraft:stone", wantID: 1, wantMeta: 0, wantOriginal: true, wantName: "minecraft:stone"},
+		{name: "minecraft:granite", wantID: 1, wantMeta: 1, wantOriginal: true, wantName: "minecraft:granite"},
+		{name: "minecraft:red_wool", wantID: 35, wantMeta: 14, wantOriginal: true, wantName: "minecraft:red_wool"},
+		{name: "minecraft:red_dye", wantID: 351, wantMeta: 1, wantOriginal: true, wantName: "minecraft:red_dye"},
+		{name: "minecraft:spruce_planks", wantID: 5, wantMeta: 1, wantOriginal: true, wantName: "minecraft:spruce_planks"},
+		{name: "minecraft:netherite_ingot", wantID: 264, wantMeta: 0, wantOriginal: false, wantName: "minecraft:diamond"},
+		{name: "minecraft:spyglass", wantID: 280, wantMeta: 0, wantOriginal: false, wantName: "minecraft:stick"},
+		{name: "tedac:custom", wantID: 421, wantMeta: 0, wantOriginal: false, wantName: "minecraft:name_tag"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			id, meta, original := ItemByName(test.name, 0)
+			if id != test.wantID || meta != test.wantMeta || original != test.wantOriginal {
+				t.Fatalf("ItemByName(%v) = (%v, %v, %v), want (%v, %v, %v)", test.name, id, meta, original, test.wantID, test.wantMeta, test.wantOriginal)
+			}
+			name, meta, ok := ItemByID(id, meta)
+			if !ok || name != test.wantName || meta != 0 {
+				t.Errorf("ItemByID(%v, %v) = (%v, %v, %v), want (%v, 0, true)", id, test.wantMeta, name, meta, ok, test.wantName)
+			}
+		})
+	}
+}
+
+func TestItemByIDUnknown(t *testing.T) {
+	if name, _, ok := ItemByID(32000, 0); ok {
+		t.Errorf("ItemByID(32000, 0) = %v, want no item", name)
+	}
+}
@@ -0,0 +1,394 @@
+package legacymappings
+
+import (
+	"strings"
+)
+
+// itemVariant is an item in v1.12.0 identified by its name and metadata value.
+type itemVariant struct {
+	name string
+	meta int16
+}
+
+var (
+	// itemEquivalents maps the names of items that were split up into separate items after v1.12.0 to the item
+	// and metadata value they were in v1.12.0. These items are identical on both versions.
+	itemEquivalents = map[string]itemVariant{
+		"minecraft:coal":                           {"minecraft:coal", 0},
+		"minecraft:charcoal":                       {"minecraft:coal", 1},
+		"minecraft:bucket":                         {"minecraft:bucket", 0},
+		"minecraft:milk_bucket":                    {"minecraft:bucket", 1},
+		"minecraft:cod_bucket":                     {"minecraft:bucket", 2},
+		"minecraft:salmon_bucket":                  {"minecraft:bucket", 3},
+		"minecraft:tropical_fish_bucket":           {"minecraft:bucket", 4},
+		"minecraft:pufferfish_bucket":              {"minecraft:bucket", 5},
+		"minecraft:water_bucket":                   {"minecraft:bucket", 8},
+		"minecraft:lava_bucket":                    {"minecraft:bucket", 10},
+		"minecraft:ink_sac":                        {"minecraft:dye", 0},
+		"minecraft:cocoa_beans":                    {"minecraft:dye", 3},
+		"minecraft:lapis_lazuli":                   {"minecraft:dye", 4},
+		"minecraft:bone_meal":                      {"minecraft:dye", 15},
+		"minecraft:granite":                        {"minecraft:stone", 1},
+		"minecraft:polished_granite":               {"minecraft:stone", 2},
+		"minecraft:diorite":                        {"minecraft:stone", 3},
+		"minecraft:polished_diorite":               {"minecraft:stone", 4},
+		"minecraft:andesite":                       {"minecraft:stone", 5},
+		"minecraft:polished_andesite":              {"minecraft:stone", 6},
+		"minecraft:grass_block":                    {"minecraft:grass", 0},
+		"minecraft:coarse_dirt":                    {"minecraft:dirt", 1},
+		"minecraft:red_sand":                       {"minecraft:sand", 1},
+		"minecraft:wet_sponge":                     {"minecraft:sponge", 1},
+		"minecraft:sea_lantern":                    {"minecraft:sealantern", 0},
+		"minecraft:stone_bricks":                   {"minecraft:stonebrick", 0},
+		"minecraft:mossy_stone_bricks":             {"minecraft:stonebrick", 1},
+		"minecraft:cracked_stone_bricks":           {"minecraft:stonebrick", 2},
+		"minecraft:chiseled_stone_bricks":          {"minecraft:stonebrick", 3},
+		"minecraft:chiseled_sandstone":             {"minecraft:sandstone", 1},
+		"minecraft:cut_sandstone":                  {"minecraft:sandstone", 2},
+		"minecraft:smooth_sandstone":               {"minecraft:sandstone", 3},
+		"minecraft:chiseled_red_sandstone":         {"minecraft:red_sandstone", 1},
+		"minecraft:cut_red_sandstone":              {"minecraft:red_sandstone", 2},
+		"minecraft:smooth_red_sandstone":           {"minecraft:red_sandstone", 3},
+		"minecraft:chiseled_quartz_block":          {"minecraft:quartz_block", 1},
+		"minecraft:quartz_pillar":                  {"minecraft:quartz_block", 2},
+		"minecraft:smooth_quartz":                  {"minecraft:quartz_block", 3},
+		"minecraft:dark_prismarine":                {"minecraft:prismarine", 1},
+		"minecraft:prismarine_bricks":              {"minecraft:prismarine", 2},
+		"minecraft:purpur_pillar":                  {"minecraft:purpur_block", 2},
+		"minecraft:chipped_anvil":                  {"minecraft:anvil", 4},
+		"minecraft:damaged_anvil":                  {"minecraft:anvil", 8},
+		"minecraft:dandelion":                      {"minecraft:yellow_flower", 0},
+		"minecraft:poppy":                          {"minecraft:red_flower", 0},
+		"minecraft:blue_orchid":                    {"minecraft:red_flower", 1},
+		"minecraft:allium":                         {"minecraft:red_flower", 2},
+		"minecraft:azure_bluet":                    {"minecraft:red_flower", 3},
+		"minecraft:red_tulip":                      {"minecraft:red_flower", 4},
+		"minecraft:orange_tulip":                   {"minecraft:red_flower", 5},
+		"minecraft:white_tulip":                    {"minecraft:red_flower", 6},
+		"minecraft:pink_tulip":                     {"minecraft:red_flower", 7},
+		"minecraft:oxeye_daisy":                    {"minecraft:red_flower", 8},
+		"minecraft:short_grass":                    {"minecraft:tallgrass", 1},
+		"minecraft:fern":                           {"minecraft:tallgrass", 2},
+		"minecraft:sunflower":                      {"minecraft:double_plant", 0},
+		"minecraft:lilac":                          {"minecraft:double_plant", 1},
+		"minecraft:tall_grass":                     {"minecraft:double_plant", 2},
+		"minecraft:large_fern":                     {"minecraft:double_plant", 3},
+		"minecraft:rose_bush":                      {"minecraft:double_plant", 4},
+		"minecraft:peony":                          {"minecraft:double_plant", 5},
+		"minecraft:skeleton_skull":                 {"minecraft:skull", 0},
+		"minecraft:wither_skeleton_skull":          {"minecraft:skull", 1},
+		"minecraft:zombie_head":                    {"minecraft:skull", 2},
+		"minecraft:player_head":                    {"minecraft:skull", 3},
+		"minecraft:creeper_head":                   {"minecraft:skull", 4},
+		"minecraft:dragon_head":                    {"minecraft:skull", 5},
+		"minecraft:infested_stone":                 {"minecraft:monster_egg", 0},
+		"minecraft:infested_cobblestone":           {"minecraft:monster_egg", 1},
+		"minecraft:infested_stone_bricks":          {"minecraft:monster_egg", 2},
+		"minecraft:infested_mossy_stone_bricks":    {"minecraft:monster_egg", 3},
+		"minecraft:infested_cracked_stone_bricks":  {"minecraft:monster_egg", 4},
+		"minecraft:infested_chiseled_stone_bricks": {"minecraft:monster_egg", 5},
+	}
+	// itemSubstitutes maps the names of items added after v1.12.0 to a similar item that existed in v1.12.0.
+	itemSubstitutes = map[string]itemVariant{
+		"minecraft:deepslate":           {"minecraft:stone", 0},
+		"minecraft:cobbled_deepslate":   {"minecraft:cobblestone", 0},
+		"minecraft:polished_deepslate":  {"minecraft:stone", 6},
+		"minecraft:deepslate_bricks":    {"minecraft:stonebrick", 0},
+		"minecraft:deepslate_tiles":     {"minecraft:stonebrick", 0},
+		"minecraft:chiseled_deepslate":  {"minecraft:stonebrick", 3},
+		"minecraft:tuff":                {"minecraft:stone", 5},
+		"minecraft:calcite":             {"minecraft:stone", 3},
+		"minecraft:copper_block":        {"minecraft:iron_block", 0},
+		"minecraft:cut_copper":          {"minecraft:iron_block", 0},
+		"minecraft:raw_copper":          {"minecraft:iron_ingot", 0},
+		"minecraft:raw_iron":            {"minecraft:iron_ingot", 0},
+		"minecraft:raw_gold":            {"minecraft:gold_ingot", 0},
+		"minecraft:raw_copper_block":    {"minecraft:iron_block", 0},
+		"minecraft:raw_iron_block":      {"minecraft:iron_block", 0},
+		"minecraft:raw_gold_block":      {"minecraft:gold_block", 0},
+		"minecraft:netherite_ingot":     {"minecraft:diamond", 0},
+		"minecraft:netherite_scrap":     {"minecraft:diamond", 0},
+		"minecraft:ancient_debris":      {"minecraft:diamond_ore", 0},
+		"minecraft:blackstone":          {"minecraft:cobblestone", 0},
+		"minecraft:polished_blackstone": {"minecraft:stone", 0},
+		"minecraft:basalt":              {"minecraft:stone", 0},
+		"minecraft:smooth_basalt":       {"minecraft:stone", 0},
+		"minecraft:mud":                 {"minecraft:dirt", 0},
+		"minecraft:packed_mud":          {"minecraft:dirt", 0},
+		"minecraft:mud_bricks":          {"minecraft:brick_block", 0},
+		"minecraft:crimson_nylium":      {"minecraft:netherrack", 0},
+		"minecraft:warped_nylium":       {"minecraft:netherrack", 0},
+		"minecraft:soul_soil":           {"minecraft:soul_sand", 0},
+		"minecraft:crying_obsidian":     {"minecraft:obsidian", 0},
+		"minecraft:amethyst_block":      {"minecraft:purpur_block", 0},
+		"minecraft:amethyst_shard":      {"minecraft:prismarine_shard", 0},
+		"minecraft:moss_block":          {"minecraft:grass", 0},
+		"minecraft:moss_carpet":         {"minecraft:carpet", 5},
+		"minecraft:tinted_glass":        {"minecraft:stained_glass", 15},
+		"minecraft:glow_ink_sac":        {"minecraft:dye", 0},
+		"minecraft:glow_frame":          {"minecraft:frame", 0},
+		"minecraft:honeycomb":           {"minecraft:dye", 14},
+		"minecraft:chain":               {"minecraft:iron_bars", 0},
+		"minecraft:soul_torch":          {"minecraft:torch", 0},
+		"minecraft:soul_lantern":        {"minecraft:lantern", 0},
+		"minecraft:soul_campfire":       {"minecraft:campfire", 0},
+		"minecraft:spyglass":            {"minecraft:stick", 0},
+		"minecraft:brush":               {"minecraft:feather", 0},
+		"minecraft:mace":                {"minecraft:iron_axe", 0},
+		"minecraft:bundle":              {"minecraft:leather", 0},
+		"minecraft:echo_shard":          {"minecraft:prismarine_shard", 0},
+		"minecraft:recovery_compass":    {"minecraft:compass", 0},
+		"minecraft:lodestone_compass":   {"minecraft:compass", 0},
+		"minecraft:goat_horn":           {"minecraft:bone", 0},
+		"minecraft:wind_charge":         {"minecraft:snowball", 0},
+		"minecraft:breeze_rod":          {"minecraft:blaze_rod", 0},
+	}
+	// itemSuffixSubstitutes maps the suffixes of the names of items added after v1.12.0 to a similar item that
+	// existed in v1.12.0. They are used for items not present in itemSubstitutes.
+	itemSuffixSubstitutes = []struct {
+		suffix string
+		item   itemVariant
+	}{
+		{"_trapdoor", itemVariant{"minecraft:trapdoor", 0}},
+		{"_door", itemVariant{"minecraft:wooden_door", 0}},
+		{"_button", itemVariant{"minecraft:wooden_button", 0}},
+		{"_pressure_plate", itemVariant{"minecraft:wooden_pressure_plate", 0}},
+		{"_fence_gate", itemVariant{"minecraft:fence_gate", 0}},
+		{"_fence", itemVariant{"minecraft:fence", 0}},
+		{"_stairs", itemVariant{"minecraft:stone_stairs", 0}},
+		{"_wall", itemVariant{"minecraft:cobblestone_wall", 0}},
+		{"_slab", itemVariant{"minecraft:wooden_slab", 0}},
+		{"_wood", itemVariant{"minecraft:log", 0}},
+		{"_hyphae", itemVariant{"minecraft:log", 0}},
+		{"_planks", itemVariant{"minecraft:planks", 0}},
+		{"_log", itemVariant{"minecraft:log", 0}},
+		{"_stem", itemVariant{"minecraft:log", 0}},
+		{"_leaves", itemVariant{"minecraft:leaves", 0}},
+		{"_sapling", itemVariant{"minecraft:sapling", 0}},
+		{"_sign", itemVariant{"minecraft:sign", 0}},
+		{"_boat", itemVariant{"minecraft:boat", 0}},
+		{"_raft", itemVariant{"minecraft:boat", 0}},
+		{"_candle", itemVariant{"minecraft:torch", 0}},
+		{"_bundle", itemVariant{"minecraft:leather", 0}},
+		{"_spawn_egg", itemVariant{"minecraft:spawn_egg", 0}},
+		{"_pottery_sherd", itemVariant{"minecraft:brick", 0}},
+		{"_smithing_template", itemVariant{"minecraft:paper", 0}},
+		{"_banner_pattern", itemVariant{"minecraft:paper", 0}},
+	}
+
+	// blockSubstitutes maps the names of blocks added after v1.12.0 to the name of a similar block in the latest
+	// version that existed in v1.12.0.
+	blockSubstitutes = map[string]string{
+		"minecraft:deepslate":             "minecraft:stone",
+		"minecraft:cobbled_deepslate":     "minecraft:cobblestone",
+		"minecraft:polished_deepslate":    "minecraft:polished_andesite",
+		"minecraft:deepslate_bricks":      "minecraft:stone_bricks",
+		"minecraft:deepslate_tiles":       "minecraft:stone_bricks",
+		"minecraft:chiseled_deepslate":    "minecraft:chiseled_stone_bricks",
+		"minecraft:infested_deepslate":    "minecraft:infested_stone",
+		"minecraft:reinforced_deepslate":  "minecraft:obsidian",
+		"minecraft:tuff":                  "minecraft:andesite",
+		"minecraft:calcite":               "minecraft:diorite",
+		"minecraft:copper_block":          "minecraft:iron_block",
+		"minecraft:cut_copper":            "minecraft:iron_block",
+		"minecraft:raw_copper_block":      "minecraft:iron_block",
+		"minecraft:raw_iron_block":        "minecraft:iron_block",
+		"minecraft:raw_gold_block":        "minecraft:gold_block",
+		"minecraft:netherite_block":       "minecraft:diamond_block",
+		"minecraft:ancient_debris":        "minecraft:diamond_ore",
+		"minecraft:nether_gold_ore":       "minecraft:quartz_ore",
+		"minecraft:blackstone":            "minecraft:cobblestone",
+		"minecraft:polished_blackstone":   "minecraft:stone",
+		"minecraft:basalt":                "minecraft:stone",
+		"minecraft:smooth_basalt":         "minecraft:stone",
+		"minecraft:mud":                   "minecraft:dirt",
+		"minecraft:packed_mud":            "minecraft:dirt",
+		"minecraft:mud_bricks":            "minecraft:brick_block",
+		"minecraft:crimson_nylium":        "minecraft:netherrack",
+		"minecraft:warped_nylium":         "minecraft:netherrack",
+		"minecraft:soul_soil":             "minecraft:soul_sand",
+		"minecraft:soul_fire":             "minecraft:fire",
+		"minecraft:crying_obsidian":       "minecraft:obsidian",
+		"minecraft:amethyst_block":        "minecraft:purpur_block",
+		"minecraft:moss_block":            "minecraft:grass_block",
+		"minecraft:moss_carpet":           "minecraft:lime_carpet",
+		"minecraft:tinted_glass":          "minecraft:black_stained_glass",
+		"minecraft:glow_frame":            "minecraft:frame",
+		"minecraft:chain":                 "minecraft:iron_bars",
+		"minecraft:soul_torch":            "minecraft:torch",
+		"minecraft:soul_lantern":          "minecraft:lantern",
+		"minecraft:soul_campfire":         "minecraft:campfire",
+		"minecraft:shroomlight":           "minecraft:glowstone",
+		"minecraft:ochre_froglight":       "minecraft:glowstone",
+		"minecraft:verdant_froglight":     "minecraft:glowstone",
+		"minecraft:pearlescent_froglight": "minecraft:glowstone",
+	}
+	// blockSuffixSubstitutes maps the suffixes of the names of blocks added after v1.12.0 to the name of a similar
+	// block in the latest version that existed in v1.12.0. They are used for blocks not present in blockSubstitutes.
+	blockSuffixSubstitutes = []struct {
+		suffix, name string
+	}{
+		{"_trapdoor", "minecraft:trapdoor"},
+		{"_door", "minecraft:wooden_door"},
+		{"_button", "minecraft:wooden_button"},
+		{"_pressure_plate", "minecraft:wooden_pressure_plate"},
+		{"_fence_gate", "minecraft:fence_gate"},
+		{"_fence", "minecraft:oak_fence"},
+		{"_stairs", "minecraft:stone_stairs"},
+		{"_double_slab", "minecraft:cobblestone_double_slab"},
+		{"_slab", "minecraft:cobblestone_slab"},
+		{"_wall", "minecraft:cobblestone_wall"},
+		{"_planks", "minecraft:oak_planks"},
+		{"_log", "minecraft:oak_log"},
+		{"_stem", "minecraft:oak_log"},
+		{"_wood", "minecraft:oak_wood"},
+		{"_hyphae", "minecraft:oak_wood"},
+		{"_leaves", "minecraft:oak_leaves"},
+		{"_wall_sign", "minecraft:wall_sign"},
+		{"_hanging_sign", "minecraft:wall_sign"},
+		{"_standing_sign", "minecraft:standing_sign"},
+		{"_carpet", "minecraft:white_carpet"},
+		{"_candle", "minecraft:torch"},
+	}
+
+	// substituteReplacements are replacements made to the names of blocks and items added after v1.12.0, in order,
+	// in an attempt to find a similar block or item that existed in v1.12.0.
+	substituteReplacements = []struct {
+		old, new string
+	}{
+		{"waxed_", ""},
+		{"exposed_", ""},
+		{"weathered_", ""},
+		{"oxidized_", ""},
+		{"cobbled_deepslate", "cobblestone"},
+		{"polished_deepslate", "polished_andesite"},
+		{"deepslate_brick", "stone_brick"},
+		{"deepslate_tile", "stone_brick"},
+		{"deepslate_", ""},
+		{"polished_blackstone_brick", "stone_brick"},
+		{"polished_blackstone", "stone"},
+		{"blackstone", "cobblestone"},
+		{"mud_brick", "brick"},
+		{"tuff_brick", "stone_brick"},
+		{"polished_tuff", "polished_andesite"},
+		{"tuff", "andesite"},
+		{"cut_copper", "stone_brick"},
+		{"copper", "iron"},
+		{"netherite", "diamond"},
+		{"pale_oak_", "oak_"},
+		{"cherry_", "oak_"},
+		{"mangrove_", "oak_"},
+		{"bamboo_mosaic", "bamboo"},
+		{"bamboo_", "oak_"},
+		{"crimson_", "acacia_"},
+		{"warped_", "dark_oak_"},
+	}
+)
+
+// itemVariantsToNames maps the items and metadata values in v1.12.0 present in itemEquivalents back to the name
+// of the item in the latest version.
+var itemVariantsToNames = map[itemVariant]string{}
+
+// colours holds the names of all colours in the latest version, ordered by their metadata value in v1.12.0.
+var colours = []string{
+	"white", "orange", "magenta", "light_blue", "yellow", "lime", "pink", "gray",
+	"light_gray", "cyan", "purple", "blue", "brown", "green", "red", "black",
+}
+
+// woodTypes holds the names of all wood types that existed in v1.12.0, ordered by their metadata value.
+var woodTypes = []string{"oak", "spruce", "birch", "jungle", "acacia", "dark_oak"}
+
+// init registers the equivalents of all coloured and wooden items that were split up after v1.12.0.
+func init() {
+	for i, c := range colours {
+		meta := int16(i)
+		itemEquivalents["minecraft:"+c+"_wool"] = itemVariant{"minecraft:wool", meta}
+		itemEquivalents["minecraft:"+c+"_carpet"] = itemVariant{"minecraft:carpet", meta}
+		itemEquivalents["minecraft:"+c+"_concrete"] = itemVariant{"minecraft:concrete", meta}
+		itemEquivalents["minecraft:"+c+"_concrete_powder"] = itemVariant{"minecraft:concrete_powder", meta}
+		itemEquivalents["minecraft:"+c+"_stained_glass"] = itemVariant{"minecraft:stained_glass", meta}
+		itemEquivalents["minecraft:"+c+"_stained_glass_pane"] = itemVariant{"minecraft:stained_glass_pane", meta}
+		itemEquivalents["minecraft:"+c+"_terracotta"] = itemVariant{"minecraft:stained_hardened_clay", meta}
+		itemEquivalents["minecraft:"+c+"_shulker_box"] = itemVariant{"minecraft:shulker_box", meta}
+		// Dyes are ordered the other way around, starting with black.
+		itemEquivalents["minecraft:"+c+"_dye"] = itemVariant{"minecraft:dye", 15 - meta}
+	}
+	for i, w := range woodTypes {
+		meta := int16(i)
+		itemEquivalents["minecraft:"+w+"_planks"] = itemVariant{"minecraft:planks", meta}
+		itemEquivalents["minecraft:"+w+"_sapling"] = itemVariant{"minecraft:sapling", meta}
+		itemEquivalents["minecraft:"+w+"_slab"] = itemVariant{"minecraft:wooden_slab", meta}
+		itemEquivalents["minecraft:"+w+"_fence"] = itemVariant{"minecraft:fence", meta}
+		itemEquivalents["minecraft:"+w+"_boat"] = itemVariant{"minecraft:boat", meta}
+		if i < 4 {
+			itemEquivalents["minecraft:"+w+"_log"] = itemVariant{"minecraft:log", meta}
+			itemEquivalents["minecraft:"+w+"_leaves"] = itemVariant{"minecraft:leaves", meta}
+		} else {
+			itemEquivalents["minecraft:"+w+"_log"] = itemVariant{"minecraft:log2", meta - 4}
+			itemEquivalents["minecraft:"+w+"_leaves"] = itemVariant{"minecraft:leaves2", meta - 4}
+		}
+	}
+	for name, v := range itemEquivalents {
+		itemVariantsToNames[v] = name
+	}
+}
+
+// substituteItem finds an item that existed in v1.12.0 that is similar to the item with the name passed. False is
+// returned if no similar item could be found.
+func substituteItem(name string) (itemVariant, bool) {
+	exists := func(v itemVariant) bool {
+		_, ok := itemNamesToIDs[v.name]
+		return ok
+	}
+	if v, ok := itemSubstitutes[name]; ok && exists(v) {
+		return v, true
+	}
+	for _, r := range substituteReplacements {
+		if !strings.Contains(name, r.old) {
+			continue
+		}
+		name = strings.Replace(name, r.old, r.new, 1)
+		if v, ok := itemEquivalents[name]; ok && exists(v) {
+			return v, true
+		}
+		if v, ok := itemSubstitutes[name]; ok && exists(v) {
+			return v, true
+		}
+		if v := (itemVariant{name: name}); exists(v) {
+			return v, true
+		}
+	}
+	for _, s := range itemSuffixSubstitutes {
+		if strings.HasSuffix(name, s.suffix) && exists(s.item) {
+			return s.item, true
+		}
+	}
+	return itemVariant{}, false
+}
+
+// substituteBlock finds the name of a block in the latest version that existed in v1.12.0 that is similar to the
+// block with the name passed. The function passed is used to check if a block existed in v1.12.0. False is
+// returned if no similar block could be found.
+func substituteBlock(name string, exists func(name string) bool) (string, bool) {
+	if sub, ok := blockSubstitutes[name]; ok && exists(sub) {
+		return sub, true
+	}
+	for _, r := range substituteReplacements {
+		if !strings.Contains(name, r.old) {
+			continue
+		}
+		name = strings.Replace(name, r.old, r.new, 1)
+		if sub, ok := blockSubstitutes[name]; ok && exists(sub) {
+			return sub, true
+		}
+		if exists(name) {
+			return name, true
+		}
+	}
+	for _, s := range blockSuffixSubstitutes {
+		if strings.HasSuffix(name, s.suffix) && exists(s.name) {
+			return s.name, true
+		}
+	}
+	return "", false
+}
@@ -43,12 +43,13 @@ const (
 	CommandArgSuffixed = 0x1000000
 	CommandArgSoftEnum = 0x4000000
 
-	CommandArgTypeInt = iota + 1
-	CommandArgTypeFloat
-	CommandArgTypeValue
-	CommandArgTypeWildcardInt
-	CommandArgTypeOperator
-	CommandArgTypeTarget
+	CommandArgTypeInt            = 0x01
+	CommandArgTypeFloat          = 0x02
+	CommandArgTypeValue          = 0x03
+	CommandArgTypeWildcardInt    = 0x04
+	CommandArgTypeOperator       = 0x05
+	CommandArgTypeTarget         = 0x06
+	CommandArgTypeWildcardTarget = 0x07
 
 	CommandArgTypeFilepath = 0x0e
 	CommandArgTypeString   = 0x1b
@@ -2,242 +2,310 @@ package legacyprotocol
 
 import (
 	"github.com/sandertv/gophertunnel/minecraft/protocol"
-	"math"
 )
 
-// DowngradeEntityMetadata downgrades entity metadata from latest version to legacy version.
-func DowngradeEntityMetadata(data map[uint32]any) map[uint32]any {
-	data = downgradeKey(data)
-
-	var flag1, flag2 int64
-	if v, ok := data[protocol.EntityDataKeyFlags]; ok {
-		flag1 = v.(int64)
+var (
+	// entityMetadataKeys maps every entity metadata key of the latest version that exists in v1.12.0 to its key in
+	// v1.12.0. Keys not present did not exist in v1.12.0 and are dropped when downgrading.
+	entityMetadataKeys = map[uint32]uint32{
+		protocol.EntityDataKeyFlags:                            0,
+		protocol.EntityDataKeyStructuralIntegrity:              1,
+		protocol.EntityDataKeyVariant:                          2,
+		protocol.EntityDataKeyColorIndex:                       3,
+		protocol.EntityDataKeyName:                             4,
+		protocol.EntityDataKeyOwner:                            5,
+		protocol.EntityDataKeyTarget:                           6,
+		protocol.EntityDataKeyAirSupply:                        7,
+		protocol.EntityDataKeyEffectColor:                      8,
+		protocol.EntityDataKeyEffectAmbience:                   9,
+		protocol.EntityDataKeyJumpDuration:                     10,
+		protocol.EntityDataKeyHurt:                             11,
+		protocol.EntityDataKeyHurtDirection:                    12,
+		protocol.EntityDataKeyRowTimeLeft:                      13,
+		protocol.EntityDataKeyRowTimeRight:                     14,
+		protocol.EntityDataKeyValue:                            15,
+		protocol.EntityDataKeyDisplayTileRuntimeID:             16,
+		protocol.EntityDataKeyDisplayOffset:                    17,
+		protocol.EntityDataKeyCustomDisplay:                    18,
+		protocol.EntityDataKeySwell:                            19,
+		protocol.EntityDataKeyOldSwell:                         20,
+		protocol.EntityDataKeySwellDirection:                   21,
+		protocol.EntityDataKeyChargeAmount:                     22,
+		protocol.EntityDataKeyCarryBlockRuntimeID:              23,
+		protocol.EntityDataKeyClientEvent:                      24,
+		protocol.EntityDataKeyUsingItem:                        25,
+		protocol.EntityDataKeyPlayerFlags:                      26,
+		protocol.EntityDataKeyPlayerIndex:                      27,
+		protocol.EntityDataKeyBedPosition:                      28,
+		protocol.EntityDataKeyPowerX:                           29,
+		protocol.EntityDataKeyPowerY:                           30,
+		protocol.EntityDataKeyPowerZ:                           31,
+		protocol.EntityDataKeyAuxPower:                         32,
+		protocol.EntityDataKeyFishX:                            33,
+		protocol.EntityDataKeyFishZ:                            34,
+		protocol.EntityDataKeyFishAngle:                        35,
+		protocol.EntityDataKeyAuxValueData:                     36,
+		protocol.EntityDataKeyLeashHolder:                      37,
+		protocol.EntityDataKeyScale:                            38,
+		protocol.EntityDataKeyHasNPC:                           39,
+		protocol.EntityDataKeyNPCData:                          40,
+		protocol.EntityDataKeyActions:                          41,
+		protocol.EntityDataKeyAirSupplyMax:                     42,
+		protocol.EntityDataKeyMarkVariant:                      43,
+		protocol.EntityDataKeyContainerType:                    44,
+		protocol.EntityDataKeyContainerSize:                    45,
+		protocol.EntityDataKeyContainerStrengthModifier:        46,
+		protocol.EntityDataKeyBlockTarget:                      47,
+		protocol.EntityDataKeyInventory:                        48,
+		protocol.EntityDataKeyTargetA:                          49,
+		protocol.EntityDataKeyTargetB:                          50,
+		protocol.EntityDataKeyTargetC:                          51,
+		protocol.EntityDataKeyAerialAttack:                     52,
+		protocol.EntityDataKeyWidth:                            53,
+		protocol.EntityDataKeyHeight:                           54,
+		protocol.EntityDataKeyFuseTime:                         55,
+		protocol.EntityDataKeySeatOffset:                       56,
+		protocol.EntityDataKeySeatLockPassengerRotation:        57,
+		protocol.EntityDataKeySeatLockPassengerRotationDegrees: 58,
+		protocol.EntityDataKeySeatRotationOffset:               59,
+		protocol.EntityDataKeyDataRadius:                       60,
+		protocol.EntityDataKeyDataWaiting:                      61,
+		protocol.EntityDataKeyDataParticle:                     62,
+		protocol.EntityDataKeyPeekID:                           63,
+		protocol.EntityDataKeyAttachFace:                       64,
+		protocol.EntityDataKeyAttached:                         65,
+		protocol.EntityDataKeyAttachedPosition:                 66,
+		protocol.EntityDataKeyTradeTarget:                      67,
+		protocol.EntityDataKeyCareer:                           68,
+		protocol.EntityDataKeyHasCommandBlock:                  69,
+		protocol.EntityDataKeyCommandName:                      70,
+		protocol.EntityDataKeyLastCommandOutput:                71,
+		protocol.EntityDataKeyTrackCommandOutput:               72,
+		protocol.EntityDataKeyControllingSeatIndex:             73,
+		protocol.EntityDataKeyStrength:                         74,
+		protocol.EntityDataKeyStrengthMax:                      75,
+		protocol.EntityDataKeyDataSpellCastingColor:            76,
+		protocol.EntityDataKeyDataLifetimeTicks:                77,
+		protocol.EntityDataKeyPoseIndex:                        78,
+		protocol.EntityDataKeyDataTickOffset:                   79,
+		protocol.EntityDataKeyAlwaysShowNameTag:                80,
+		protocol.EntityDataKeyColorTwoIndex:                    81,
+		protocol.EntityDataKeyNameAuthor:                       82,
+		protocol.EntityDataKeyScore:                            83,
+		protocol.EntityDataKeyBalloonAnchor:                    84,
+		protocol.EntityDataKeyPuffedState:                      85,
+		protocol.EntityDataKeyBubbleTime:                       86,
+		protocol.EntityDataKeyAgent:                            87,
+		protocol.EntityDataKeySittingAmount:                    88,
+		protocol.EntityDataKeySittingAmountPrevious:            89,
+		protocol.EntityDataKeyEatingCounter:                    90,
+		protocol.EntityDataKeyFlagsTwo:                         91,
+		protocol.EntityDataKeyLayingAmount:                     92,
+		protocol.EntityDataKeyLayingAmountPrevious:             93,
+		protocol.EntityDataKeyDataDuration:                     94,
+		protocol.EntityDataKeyDataSpawnTime:                    95,
+		protocol.EntityDataKeyDataChangeRate:                   96,
+		protocol.EntityDataKeyDataChangeOnPickup:               97,
+		protocol.EntityDataKeyDataPickupCount:                  98,
+		protocol.EntityDataKeyInteractText:                     99,
+		protocol.EntityDataKeyTradeTier:                        100,
+		protocol.EntityDataKeyMaxTradeTier:                     101,
+		protocol.EntityDataKeyTradeExperience:                  102,
+		protocol.EntityDataKeySkinID:                           103,
+		protocol.EntityDataKeySpawningFrames:                   104,
+		protocol.EntityDataKeyCommandBlockTickDelay:            105,
+		protocol.EntityDataKeyCommandBlockExecuteOnFirstTick:   106,
+		protocol.EntityDataKeyAmbientSoundInterval:             107,
+		protocol.EntityDataKeyAmbientSoundIntervalRange:        108,
+		protocol.EntityDataKeyAmbientSoundEventName:            109,
 	}
-	if v, ok := data[protocol.EntityDataKeyFlagsTwo]; ok {
-		flag2 = v.(int64)
+	// entityFlags holds every entity flag of the latest version that exists in v1.12.0, ordered by its index in
+	// v1.12.0. Flags not present did not exist in v1.12.0 and are dropped when downgrading.
+	entityFlags = []uint32{
+		protocol.EntityDataFlagOnFire,
+		protocol.EntityDataFlagSneaking,
+		protocol.EntityDataFlagRiding,
+		protocol.EntityDataFlagSprinting,
+		protocol.EntityDataFlagUsingItem,
+		protocol.EntityDataFlagInvisible,
+		protocol.EntityDataFlagTempted,
+		protocol.EntityDataFlagInLove,
+		protocol.EntityDataFlagSaddled,
+		protocol.EntityDataFlagPowered,
+		protocol.EntityDataFlagIgnited,
+		protocol.EntityDataFlagBaby,
+		protocol.EntityDataFlagConverting,
+		protocol.EntityDataFlagCritical,
+		protocol.EntityDataFlagShowName,
+		protocol.EntityDataFlagAlwaysShowName,
+		protocol.EntityDataFlagNoAI,
+		protocol.EntityDataFlagSilent,
+		protocol.EntityDataFlagWallClimbing,
+		protocol.EntityDataFlagClimb,
+		protocol.EntityDataFlagSwim,
+		protocol.EntityDataFlagFly,
+		protocol.EntityDataFlagWalk,
+		protocol.EntityDataFlagResting,
+		protocol.EntityDataFlagSitting,
+		protocol.EntityDataFlagAngry,
+		protocol.EntityDataFlagInterested,
+		protocol.EntityDataFlagCharged,
+		protocol.EntityDataFlagTamed,
+		protocol.EntityDataFlagOrphaned,
+		protocol.EntityDataFlagLeashed,
+		protocol.EntityDataFlagSheared,
+		protocol.EntityDataFlagGliding,
+		protocol.EntityDataFlagElder,
+		protocol.EntityDataFlagMoving,
+		protocol.EntityDataFlagBreathing,
+		protocol.EntityDataFlagChested,
+		protocol.EntityDataFlagStackable,
+		protocol.EntityDataFlagShowBottom,
+		protocol.EntityDataFlagStanding,
+		protocol.EntityDataFlagShaking,
+		protocol.EntityDataFlagIdling,
+		protocol.EntityDataFlagCasting,
+		protocol.EntityDataFlagCharging,
+		protocol.EntityDataFlagKeyboardControlled,
+		protocol.EntityDataFlagPowerJump,
+		protocol.EntityDataFlagLingering,
+		protocol.EntityDataFlagHasCollision,
+		protocol.EntityDataFlagHasGravity,
+		protocol.EntityDataFlagFireImmune,
+		protocol.EntityDataFlagDancing,
+		protocol.EntityDataFlagEnchanted,
+		protocol.EntityDataFlagReturnTrident,
+		protocol.EntityDataFlagContainerPrivate,
+		protocol.EntityDataFlagTransforming,
+		protocol.EntityDataFlagDamageNearbyMobs,
+		protocol.EntityDataFlagSwimming,
+		protocol.EntityDataFlagBribed,
+		protocol.EntityDataFlagPregnant,
+		protocol.EntityDataFlagLayingEgg,
+		protocol.EntityDataFlagPassengerCanPick,
+		protocol.EntityDataFlagTransitionSitting,
+		protocol.EntityDataFlagEating,
+		protocol.EntityDataFlagLayingDown,
+		protocol.EntityDataFlagSneezing,
+		protocol.EntityDataFlagTrusting,
+		protocol.EntityDataFlagRolling,
+		protocol.EntityDataFlagScared,
+		protocol.EntityDataFlagInScaffolding,
+		protocol.EntityDataFlagOverScaffolding,
+		protocol.EntityDataFlagDescendThroughBlock,
+		protocol.EntityDataFlagBlocking,
+		protocol.EntityDataFlagTransitionBlocking,
+		protocol.EntityDataFlagBlockedUsingShield,
+		protocol.EntityDataFlagBlockedUsingDamagedShield,
+		protocol.EntityDataFlagSleeping,
+		protocol.EntityDataFlagWantsToWake,
+		protocol.EntityDataFlagTradeInterest,
+		protocol.EntityDataFlagDoorBreaker,
+		protocol.EntityDataFlagBreakingObstruction,
+		protocol.EntityDataFlagDoorOpener,
+		protocol.EntityDataFlagCaptain,
+		protocol.EntityDataFlagStunned,
+		protocol.EntityDataFlagRoaring,
+		protocol.EntityDataFlagDelayedAttack,
+		protocol.EntityDataFlagAvoidingMobs,
 	}
-	if flag1 == 0 && flag2 == 0 {
-		return data
-	}
-
-	newFlag1 := flag1 & ^(^0 << (protocol.EntityDataFlagDash - 1))
-	lastHalf := flag1 & (^0 << protocol.EntityDataFlagDash)
-	lastHalf >>= 1
-	lastHalf &= math.MaxInt64
 
-	newFlag1 |= lastHalf
+	// legacyEntityMetadataKeys maps the v1.12.0 entity metadata keys in entityMetadataKeys back to the key of the
+	// latest version.
+	legacyEntityMetadataKeys = map[uint32]uint32{}
+)
 
-	if flag2 != 0 {
-		newFlag1 ^= (flag2 & 1) << 63
-		flag2 >>= 1
-		flag2 &= math.MaxInt64
+// init fills out legacyEntityMetadataKeys.
+func init() {
+	for key, legacyKey := range entityMetadataKeys {
+		legacyEntityMetadataKeys[legacyKey] = key
+	}
+}
 
-		data[protocol.EntityDataKeyFlagsTwo] = flag2
+// DowngradeEntityMetadata downgrades entity metadata from latest version to legacy version. Keys and flags that did
+// not exist in v1.12.0 are dropped, as are compound tag values, which v1.12.0 clients would read as an item. The map
+// passed is not modified.
+func DowngradeEntityMetadata(data map[uint32]any) map[uint32]any {
+	newData := make(map[uint32]any, len(data))
+	for key, value := range data {
+		legacyKey, ok := entityMetadataKeys[key]
+		if !ok {
+			continue
+		}
+		if _, ok := value.(map[string]any); ok {
+			continue
+		}
+		newData[legacyKey] = value
 	}
 
-	data[protocol.EntityDataKeyFlags] = newFlag1
-	return data
+	flags, flagsTwo, ok := entityFlagFields(data, protocol.EntityDataKeyFlagsTwo)
+	if !ok {
+		return newData
+	}
+	var legacyFlags, legacyFlagsTwo int64
+	for legacyIndex, index := range entityFlags {
+		if hasFlag(flags, flagsTwo, index) {
+			setFlag(&legacyFlags, &legacyFlagsTwo, uint32(legacyIndex))
+		}
+	}
+	setEntityFlagFields(newData, entityMetadataKeys[protocol.EntityDataKeyFlagsTwo], legacyFlags, legacyFlagsTwo)
+	return newData
 }
 
-// UpgradeEntityMetadata upgrades entity metadata from legacy version to latest version.
+// UpgradeEntityMetadata upgrades entity metadata from legacy version to latest version. The map passed is not
+// modified.
 func UpgradeEntityMetadata(data map[uint32]any) map[uint32]any {
-	data = upgradeKey(data)
+	newData := make(map[uint32]any, len(data))
+	for key, value := range data {
+		if latestKey, ok := legacyEntityMetadataKeys[key]; ok {
+			newData[latestKey] = value
+		}
+	}
 
-	var flag1, flag2 int64
-	if v, ok := data[protocol.EntityDataKeyFlags]; ok {
-		flag1 = v.(int64)
+	legacyFlags, legacyFlagsTwo, ok := entityFlagFields(data, entityMetadataKeys[protocol.EntityDataKeyFlagsTwo])
+	if !ok {
+		return newData
 	}
-	if v, ok := data[protocol.EntityDataKeyFlagsTwo]; ok {
-		flag2 = v.(int64)
+	var flags, flagsTwo int64
+	for legacyIndex, index := range entityFlags {
+		if hasFlag(legacyFlags, legacyFlagsTwo, uint32(legacyIndex)) {
+			setFlag(&flags, &flagsTwo, index)
+		}
 	}
+	setEntityFlagFields(newData, protocol.EntityDataKeyFlagsTwo, flags, flagsTwo)
+	return newData
+}
 
-	flag2 <<= 1
-	flag2 |= (flag1 >> 63) & 1
-
-	newFlag1 := flag1 & ^(^0 << (protocol.EntityDataFlagDash - 1))
-	lastHalf := flag1 & (^0<<protocol.EntityDataFlagDash - 1)
-	lastHalf <<= 1
-	newFlag1 |= lastHalf
+// entityFlagFields returns the two fields holding the flags of the entity metadata passed, of which the second one
+// is stored at the key passed. False is returned if neither field is present.
+func entityFlagFields(data map[uint32]any, flagsTwoKey uint32) (int64, int64, bool) {
+	flags, ok := data[protocol.EntityDataKeyFlags].(int64)
+	flagsTwo, okTwo := data[flagsTwoKey].(int64)
+	return flags, flagsTwo, ok || okTwo
+}
 
-	data[protocol.EntityDataKeyFlagsTwo] = flag2
-	data[protocol.EntityDataKeyFlags] = newFlag1
-	return data
+// setEntityFlagFields stores the two fields holding the flags of an entity in the entity metadata passed. The second
+// field is stored at the key passed, and only if any of its flags are set.
+func setEntityFlagFields(data map[uint32]any, flagsTwoKey uint32, flags, flagsTwo int64) {
+	data[protocol.EntityDataKeyFlags] = flags
+	if flagsTwo != 0 {
+		data[flagsTwoKey] = flagsTwo
+	}
 }
 
-// downgradeKey downgrades the latest key of an entity metadata map to the legacy key.
-func downgradeKey(data map[uint32]any) map[uint32]any {
-	newData := make(map[uint32]any)
-	for key, value := range data {
-		switch key {
-		case protocol.EntityDataKeyDataRadius:
-			key = 60
-		case protocol.EntityDataKeyDataWaiting:
-			key = 61
-		case protocol.EntityDataKeyDataParticle:
-			key = 62
-		case protocol.EntityDataKeyAttachFace:
-			key = 64
-		case protocol.EntityDataKeyAttachedPosition:
-			key = 66
-		case protocol.EntityDataKeyTradeTarget:
-			key = 67
-		case protocol.EntityDataKeyCommandName:
-			key = 70
-		case protocol.EntityDataKeyLastCommandOutput:
-			key = 71
-		case protocol.EntityDataKeyTrackCommandOutput:
-			key = 72
-		case protocol.EntityDataKeyControllingSeatIndex:
-			key = 73
-		case protocol.EntityDataKeyStrength:
-			key = 74
-		case protocol.EntityDataKeyStrengthMax:
-			key = 75
-		case protocol.EntityDataKeyDataLifetimeTicks:
-			key = 77
-		case protocol.EntityDataKeyPoseIndex:
-			key = 78
-		case protocol.EntityDataKeyDataTickOffset:
-			key = 79
-		case protocol.EntityDataKeyAlwaysShowNameTag:
-			key = 80
-		case protocol.EntityDataKeyColorTwoIndex:
-			key = 81
-		case protocol.EntityDataKeyScore:
-			key = 83
-		case protocol.EntityDataKeyBalloonAnchor:
-			key = 84
-		case protocol.EntityDataKeyPuffedState:
-			key = 85
-		case protocol.EntityDataKeyBubbleTime:
-			key = 86
-		case protocol.EntityDataKeyAgent:
-			key = 87
-		case protocol.EntityDataKeyEatingCounter:
-			key = 90
-		case protocol.EntityDataKeyFlagsTwo:
-			key = 91
-		case protocol.EntityDataKeyDataDuration:
-			key = 94
-		case protocol.EntityDataKeyDataSpawnTime:
-			key = 95
-		case protocol.EntityDataKeyDataChangeRate:
-			key = 96
-		case protocol.EntityDataKeyDataChangeOnPickup:
-			key = 97
-		case protocol.EntityDataKeyDataPickupCount:
-			key = 98
-		case protocol.EntityDataKeyInteractText:
-			key = 99
-		case protocol.EntityDataKeyTradeTier:
-			key = 100
-		case protocol.EntityDataKeyMaxTradeTier:
-			key = 101
-		case protocol.EntityDataKeyTradeExperience:
-			key = 102
-		case protocol.EntityDataKeySkinID:
-			key = 104
-		case protocol.EntityDataKeyCommandBlockTickDelay:
-			key = 105
-		case protocol.EntityDataKeyCommandBlockExecuteOnFirstTick:
-			key = 106
-		case protocol.EntityDataKeyAmbientSoundInterval:
-			key = 107
-		case protocol.EntityDataKeyAmbientSoundIntervalRange:
-			key = 108
-		case protocol.EntityDataKeyAmbientSoundEventName:
-			key = 109
-		}
-		newData[key] = value
+// hasFlag checks if the flag with the index passed is set in the two fields holding the flags of an entity.
+func hasFlag(flags, flagsTwo int64, index uint32) bool {
+	if index < 64 {
+		return flags&(1<<index) != 0
 	}
-	return newData
+	return flagsTwo&(1<<(index-64)) != 0
 }
 
-// upgradeKey upgrades the legacy key of an entity metadata map to the latest key.
-func upgradeKey(data map[uint32]any) map[uint32]any {
-	newData := make(map[uint32]any)
-	for key, value := range data {
-		switch key {
-		case 60:
-			key = protocol.EntityDataKeyDataRadius
-		case 61:
-			key = protocol.EntityDataKeyDataWaiting
-		case 62:
-			key = protocol.EntityDataKeyDataParticle
-		case 64:
-			key = protocol.EntityDataKeyAttachFace
-		case 66:
-			key = protocol.EntityDataKeyAttachedPosition
-		case 67:
-			key = protocol.EntityDataKeyTradeTarget
-		case 70:
-			key = protocol.EntityDataKeyCommandName
-		case 71:
-			key = protocol.EntityDataKeyLastCommandOutput
-		case 72:
-			key = protocol.EntityDataKeyTrackCommandOutput
-		case 73:
-			key = protocol.EntityDataKeyControllingSeatIndex
-		case 74:
-			key = protocol.EntityDataKeyStrength
-		case 75:
-			key = protocol.EntityDataKeyStrengthMax
-		case 77:
-			key = protocol.EntityDataKeyDataLifetimeTicks
-		case 78:
-			key = protocol.EntityDataKeyPoseIndex
-		case 79:
-			key = protocol.EntityDataKeyDataTickOffset
-		case 80:
-			key = protocol.EntityDataKeyAlwaysShowNameTag
-		case 81:
-			key = protocol.EntityDataKeyColorTwoIndex
-		case 83:
-			key = protocol.EntityDataKeyScore
-		case 84:
-			key = protocol.EntityDataKeyBalloonAnchor
-		case 85:
-			key = protocol.EntityDataKeyPuffedState
-		case 86:
-			key = protocol.EntityDataKeyBubbleTime
-		case 87:
-			key = protocol.EntityDataKeyAgent
-		case 90:
-			key = protocol.EntityDataKeyEatingCounter
-		case 91:
-			key = protocol.EntityDataKeyFlagsTwo
-		case 94:
-			key = protocol.EntityDataKeyDataDuration
-		case 95:
-			key = protocol.EntityDataKeyDataSpawnTime
-		case 96:
-			key = protocol.EntityDataKeyDataChangeRate
-		case 97:
-			key = protocol.EntityDataKeyDataChangeOnPickup
-		case 98:
-			key = protocol.EntityDataKeyDataPickupCount
-		case 99:
-			key = protocol.EntityDataKeyInteractText
-		case 100:
-			key = protocol.EntityDataKeyTradeTier
-		case 101:
-			key = protocol.EntityDataKeyMaxTradeTier
-		case 102:
-			key = protocol.EntityDataKeyTradeExperience
-		case 104:
-			key = protocol.EntityDataKeySkinID
-		case 105:
-			key = protocol.EntityDataKeyCommandBlockTickDelay
-		case 106:
-			key = protocol.EntityDataKeyCommandBlockExecuteOnFirstTick
-		case 107:
-			key = protocol.EntityDataKeyAmbientSoundInterval
-		case 108:
-			key = protocol.EntityDataKeyAmbientSoundIntervalRange
-		case 109:
-			key = protocol.EntityDataKeyAmbientSoundEventName
-		}
-		newData[key] = value
+// setFlag sets the flag with the index passed in the two fields holding the flags of an entity.
+func setFlag(flags, flagsTwo *int64, index uint32) {
+	if index < 64 {
+		*flags |= 1 << index
+		return
 	}
-	return newData
+	*flagsTwo |= 1 << (index - 64)
 }
@@ -0,0 +1,158 @@
+package legacyprotocol
+
+import (
+	"maps"
+	"strings"
+)
+
+const (
+	// maxEnchantmentID is the highest enchantment ID that exists in v1.12.0, which is quick charge.
+	maxEnchantmentID = 35
+	// maxPotionID is the highest potion type that exists in v1.12.0, which is long slow falling.
+	maxPotionID = 41
+)
+
+// durableSuffixes holds the suffixes of the names of all tools and armour, which store their durability in the
+// metadata value in v1.12.0.
+var durableSuffixes = []string{
+	"_sword", "_shovel", "_pickaxe", "_axe", "_hoe",
+	"_helmet", "_chestplate", "_leggings", "_boots",
+}
+
+// durableItems holds the names of all other items that store their durability in the metadata value in v1.12.0.
+var durableItems = map[string]struct{}{
+	"minecraft:bow":               {},
+	"minecraft:carrot_on_a_stick": {},
+	"minecraft:crossbow":          {},
+	"minecraft:elytra":            {},
+	"minecraft:fishing_rod":       {},
+	"minecraft:flint_and_steel":   {},
+	"minecraft:shears":            {},
+	"minecraft:shield":            {},
+	"minecraft:trident":           {},
+	"minecraft:turtle_helmet":     {},
+}
+
+// potionItems holds the names of all items of which the metadata value is a potion type.
+var potionItems = map[string]struct{}{
+	"minecraft:potion":           {},
+	"minecraft:splash_potion":    {},
+	"minecraft:lingering_potion": {},
+	"minecraft:arrow":            {},
+}
+
+// durable checks if the item with the name passed has durability.
+func durable(name string) bool {
+	if _, ok := durableItems[name]; ok {
+		return true
+	}
+	for _, suffix := range durableSuffixes {
+		if strings.HasSuffix(name, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// DowngradeItemNBT downgrades the metadata value and NBT of the item with the name passed from the latest version
+// to v1.12.0. The durability of items is moved from the Damage tag to the metadata value, and enchantments and
+// potions that did not yet exist are removed. The NBT passed is not modified.
+func DowngradeItemNBT(name string, meta int16, data map[string]any) (int16, map[string]any) {
+	if _, ok := potionItems[name]; ok && meta > maxPotionID {
+		// Newer potions are shown as water bottles.
+		meta = 0
+	}
+	if len(data) == 0 {
+		return meta, data
+	}
+	data = maps.Clone(data)
+	if damage, ok := data["Damage"].(int32); ok && durable(name) {
+		meta = int16(damage)
+		delete(data, "Damage")
+	}
+	if ench, ok := data["ench"].([]any); ok {
+		// We keep the list even if it ends up empty, so that the item still shows the enchantment glint.
+		data["ench"] = filterEnchantments(ench)
+	}
+	downgradeDisplay(data)
+	if pages, ok := data["pages"].([]any); ok {
+		data["pages"] = downgradePages(pages)
+	}
+	return meta, data
+}
+
+// UpgradeItemNBT upgrades the metadata value and NBT of the item with the name passed from v1.12.0 to the latest
+// version. Only the durability of items is moved from the metadata value to the Damage tag: the other changes
+// made by DowngradeItemNBT only remove data, which the latest version reads the same way, so there is nothing to
+// undo. The NBT passed is not modified.
+func UpgradeItemNBT(name string, meta int16, data map[string]any) (int16, map[string]any) {
+	if !durable(name) || meta <= 0 {
+		return meta, data
+	}
+	data = maps.Clone(data)
+	if data == nil {
+		data = make(map[string]any)
+	}
+	data["Damage"] = int32(meta)
+	return 0, data
+}
+
+// filterEnchantments removes all enchantments from the list passed that did not exist in v1.12.0.
+func filterEnchantments(ench []any) []any {
+	filtered := make([]any, 0, len(ench))
+	for _, e := range ench {
+		m, ok := e.(map[string]any)
+		if !ok {
+			continue
+		}
+		if id, ok := m["id"].(int16); !ok || id < 0 || id > maxEnchantmentID {
+			continue
+		}
+		filtered = append(filtered, m)
+	}
+	return filtered
+}
+
+// downgradeDisplay makes sure the custom name, lore and dye colour of an item, which are stored the same way in
+// both versions, only hold the types that v1.12.0 clients are able to read.
+func downgradeDisplay(data map[string]any) {
+	if colour, ok := data["customColor"]; ok {
+		if _, ok := colour.(int32); !ok {
+			delete(data, "customColor")
+		}
+	}
+	display, ok := data["display"].(map[string]any)
+	if !ok {
+		return
+	}
+	display = maps.Clone(display)
+	if _, ok := display["Name"].(string); !ok {
+		delete(display, "Name")
+	}
+	if lore, ok := display["Lore"].([]any); ok {
+		lines := make([]any, 0, len(lore))
+		for _, line := range lore {
+			if line, ok := line.(string); ok {
+				lines = append(lines, line)
+			}
+		}
+		display["Lore"] = lines
+	}
+	data["display"] = display
+}
+
+// downgradePages strips the pages of a book and quill or written book down to the text and photo name, which are
+// the only fields of a page v1.12.0 clients know.
+func downgradePages(pages []any) []any {
+	downgraded := make([]any, 0, len(pages))
+	for _, p := range pages {
+		m, ok := p.(map[string]any)
+		if !ok {
+			continue
+		}
+		text, _ := m["text"].(string)
+		photo, _ := m["photoname"].(string)
+		downgraded = append(downgraded, map[string]any{"text": text, "photoname": photo})
+	}
+	return downgraded
+}
@@ -0,0 +1,115 @@
+package legacyprotocol
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDowngradeItemNBT(t *testing.T) {
+	tests := []struct {
+		name     string
+		item     string
+		meta     int16
+		data     map[string]any
+		wantMeta int16
+		wantData map[string]any
+	}{
+		{
+			name:     "sword damage",
+			item:     "minecraft:diamond_sword",
+			data:     map[string]any{"Damage": int32(12)},
+			wantMeta: 12,
+			wantData: map[string]any{},
+		},
+		{
+			name:     "crossbow damage",
+			item:     "minecraft:crossbow",
+			data:     map[string]any{"Damage": int32(5)},
+			wantMeta: 5,
+			wantData: map[string]any{},
+		},
+		{
+			name:     "non-durable damage",
+			item:     "minecraft:stone",
+			data:     map[string]any{"Damage": int32(5)},
+			wantData: map[string]any{"Damage": int32(5)},
+		},
+		{
+			name:     "new potion",
+			item:     "minecraft:potion",
+			meta:     maxPotionID + 1,
+			wantMeta: 0,
+		},
+		{
+			name:     "old potion",
+			item:     "minecraft:splash_potion",
+			meta:     maxPotionID,
+			wantMeta: maxPotionID,
+		},
+		{
+			name: "enchantments",
+			item: "minecraft:crossbow",
+			data: map[string]any{"ench": []any{
+				map[string]any{"id": int16(33), "lvl": int16(1)},
+				map[string]any{"id": int16(35), "lvl": int16(3)},
+				map[string]any{"id": int16(36), "lvl": int16(1)},
+				map[string]any{"id": int16(37), "lvl": int16(1)},
+			}},
+			wantData: map[string]any{"ench": []any{
+				map[string]any{"id": int16(33), "lvl": int16(1)},
+				map[string]any{"id": int16(35), "lvl": int16(3)},
+			}},
+		},
+		{
+			name:     "only new enchantments",
+			item:     "minecraft:diamond_boots",
+			data:     map[string]any{"ench": []any{map[string]any{"id": int16(36), "lvl": int16(3)}}},
+			wantData: map[string]any{"ench": []any{}},
+		},
+		{
+			name: "display",
+			item: "minecraft:leather_helmet",
+			data: map[string]any{
+				"customColor": int64(1),
+				"display":     map[string]any{"Name": "a", "Lore": []any{"b", int32(1)}},
+			},
+			wantData: map[string]any{
+				"display": map[string]any{"Name": "a", "Lore": []any{"b"}},
+			},
+		},
+		{
+			name: "pages",
+			item: "minecraft:writable_book",
+			data: map[string]any{"pages": []any{
+				map[string]any{"text": "a", "photoname": "", "extra": int32(1)},
+			}},
+			wantData: map[string]any{"pages": []any{
+				map[string]any{"text": "a", "photoname": ""},
+			}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			meta, data := DowngradeItemNBT(test.item, test.meta, test.data)
+			if meta != test.wantMeta {
+				t.Errorf("got meta %v, want %v", meta, test.wantMeta)
+			}
+			if len(data) != 0 || len(test.wantData) != 0 {
+				if !reflect.DeepEqual(data, test.wantData) {
+					t.Errorf("got data %v, want %v", data, test.wantData)
+				}
+			}
+		})
+	}
+}
+
+func TestUpgradeItemNBT(t *testing.T) {
+	meta, data := UpgradeItemNBT("minecraft:crossbow", 5, nil)
+	if meta != 0 || data["Damage"] != int32(5) {
+		t.Errorf("got meta %v and data %v, want meta 0 and Damage 5", meta, data)
+	}
+	meta, data = UpgradeItemNBT("minecraft:stone", 5, nil)
+	if meta != 5 || data != nil {
+		t.Errorf("got meta %v and data %v, want meta 5 and no data", meta, data)
+	}
+}
@@ -0,0 +1,34 @@
+package legacypacket
+
+import (
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/sandertv/gophertunnel/minecraft/protocol"
+	"github.com/sandertv/gophertunnel/minecraft/protocol/packet"
+)
+
+// ChangeDimension is sent by the server to the client to send a dimension change screen client-side. Once the
+// screen is cleared client-side, the client will send a PlayerAction packet with the dimension change done
+// action attached.
+type ChangeDimension struct {
+	// Dimension is the dimension that the client should be changed to. The fog colour will change depending
+	// on the type of dimension, and in the Nether, the height of the world is 128 blocks.
+	Dimension int32
+	// Position is the position in the new dimension that the player is spawned in.
+	Position mgl32.Vec3
+	// Respawn specifies if the dimension change was respawn based, meaning that the player died in one
+	// dimension and got respawned into another. The client will send a PlayerAction packet with the
+	// dimension change done action if it was not a respawn.
+	Respawn bool
+}
+
+// ID ...
+func (*ChangeDimension) ID() uint32 {
+	return packet.IDChangeDimension
+}
+
+// Marshal ...
+func (pk *ChangeDimension) Marshal(io protocol.IO) {
+	io.Varint32(&pk.Dimension)
+	io.Vec3(&pk.Position)
+	io.Bool(&pk.Respawn)
+}
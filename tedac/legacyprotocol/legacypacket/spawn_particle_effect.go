@@ -0,0 +1,33 @@
+package legacypacket
+
+import (
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/sandertv/gophertunnel/minecraft/protocol"
+	"github.com/sandertv/gophertunnel/minecraft/protocol/packet"
+)
+
+// SpawnParticleEffect is sent by the server to spawn a particle effect client-side. Unlike other packets that
+// result in the appearing of particles, this packet can show particles that are not hardcoded in the client.
+// They can be added and changed through behaviour packs to implement custom particles.
+type SpawnParticleEffect struct {
+	// Dimension is the dimension that the particle is spawned in. Its exact usage is not clear, as the
+	// dimension has no direct effect on the particle.
+	Dimension byte
+	// Position is the position that the particle should be spawned at. If the position is too far away from
+	// the player, it will not show up.
+	Position mgl32.Vec3
+	// ParticleName is the name of the particle that should be shown. This name may point to a particle effect
+	// that is built-in, or to one implemented by behaviour packs.
+	ParticleName string
+}
+
+// ID ...
+func (*SpawnParticleEffect) ID() uint32 {
+	return packet.IDSpawnParticleEffect
+}
+
+func (pk *SpawnParticleEffect) Marshal(io protocol.IO) {
+	io.Uint8(&pk.Dimension)
+	io.Vec3(&pk.Position)
+	io.String(&pk.ParticleName)
+}
@@ -0,0 +1,281 @@
+package tedac
+
+import (
+	"github.com/didntpot/tedac/tedac/latestmappings"
+	"github.com/didntpot/tedac/tedac/legacymappings"
+	"github.com/didntpot/tedac/tedac/legacyprotocol/legacypacket"
+	"github.com/sandertv/gophertunnel/minecraft/protocol/packet"
+)
+
+const (
+	// legacyParticleTerrain is the ID of the terrain particle in v1.12.0, of which the event data is a block runtime ID.
+	legacyParticleTerrain = 18
+	// legacyParticleItemBreak is the ID of the item break particle in v1.12.0, of which the event data holds an
+	// item ID and metadata value.
+	legacyParticleItemBreak = 12
+)
+
+// legacyLevelEvents holds all level events that exist in v1.12.0, apart from particles. These are sent to v1.12.0
+// clients unchanged.
+var legacyLevelEvents = map[int32]struct{}{
+	packet.LevelEventSoundClick:                 {},
+	packet.LevelEventSoundClickFail:             {},
+	packet.LevelEventSoundLaunch:                {},
+	packet.LevelEventSoundOpenDoor:              {},
+	packet.LevelEventSoundFizz:                  {},
+	packet.LevelEventSoundFuse:                  {},
+	packet.LevelEventSoundPlayRecording:         {},
+	packet.LevelEventSoundGhastWarning:          {},
+	packet.LevelEventSoundGhastFireball:         {},
+	packet.LevelEventSoundBlazeFireball:         {},
+	packet.LevelEventSoundZombieWoodenDoor:      {},
+	packet.LevelEventSoundZombieDoorCrash:       {},
+	packet.LevelEventSoundZombieInfected:        {},
+	packet.LevelEventSoundZombieConverted:       {},
+	packet.LevelEventSoundEndermanTeleport:      {},
+	packet.LevelEventSoundAnvilBroken:           {},
+	packet.LevelEventSoundAnvilUsed:             {},
+	packet.LevelEventSoundAnvilLand:             {},
+	packet.LevelEventSoundInfinityArrowPickup:   {},
+	packet.LevelEventSoundTeleportEnderPearl:    {},
+	packet.LevelEventSoundAddItem:               {},
+	packet.LevelEventSoundItemFrameBreak:        {},
+	packet.LevelEventSoundItemFramePlace:        {},
+	packet.LevelEventSoundItemFrameRemoveItem:   {},
+	packet.LevelEventSoundItemFrameRotateItem:   {},
+	packet.LevelEventSoundExperienceOrbPickup:   {},
+	packet.LevelEventSoundTotemUsed:             {},
+	packet.LevelEventSoundArmorStandBreak:       {},
+	packet.LevelEventSoundArmorStandHit:         {},
+	packet.LevelEventSoundArmorStandLand:        {},
+	packet.LevelEventSoundArmorStandPlace:       {},
+	packet.LevelEventParticlesShoot:             {},
+	packet.LevelEventParticlesDestroyBlock:      {},
+	packet.LevelEventParticlesPotionSplash:      {},
+	packet.LevelEventParticlesEyeOfEnderDeath:   {},
+	packet.LevelEventParticlesMobBlockSpawn:     {},
+	packet.LevelEventParticleCropGrowth:         {},
+	packet.LevelEventParticleSoundGuardianGhost: {},
+	packet.LevelEventParticleDeathSmoke:         {},
+	packet.LevelEventParticleDenyBlock:          {},
+	packet.LevelEventParticleGenericSpawn:       {},
+	packet.LevelEventParticlesDragonEgg:         {},
+	packet.LevelEventParticlesCropEaten:         {},
+	packet.LevelEventParticlesCritical:          {},
+	packet.LevelEventParticlesTeleport:          {},
+	packet.LevelEventParticlesCrackBlock:        {},
+	packet.LevelEventParticlesBubble:            {},
+	packet.LevelEventParticlesEvaporate:         {},
+	packet.LevelEventParticlesDestroyArmorStand: {},
+	packet.LevelEventParticlesBreakingEgg:       {},
+	packet.LevelEventParticleDestroyEgg:         {},
+	packet.LevelEventStartRaining:               {},
+	packet.LevelEventStartThunderstorm:          {},
+	packet.LevelEventStopRaining:                {},
+	packet.LevelEventStopThunderstorm:           {},
+	packet.LevelEventActivateBlock:              {},
+	packet.LevelEventCauldronExplode:            {},
+	packet.LevelEventCauldronDyeArmor:           {},
+	packet.LevelEventCauldronCleanArmor:         {},
+	packet.LevelEventCauldronFillPotion:         {},
+	packet.LevelEventCauldronTakePotion:         {},
+	packet.LevelEventCauldronFillWater:          {},
+	packet.LevelEventCauldronTakeWater:          {},
+	packet.LevelEventCauldronAddDye:             {},
+	packet.LevelEventCauldronCleanBanner:        {},
+	packet.LevelEventStartBlockCracking:         {},
+	packet.LevelEventStopBlockCracking:          {},
+	packet.LevelEventAllPlayersSleeping:         {},
+}
+
+// levelEventSubstitutes maps level events added after v1.12.0 to a similar level event that v1.12.0 clients are
+// able to show. Particles are mapped to packet.LevelEventParticleLegacyEvent combined with their v1.12.0 ID.
+var levelEventSubstitutes = map[int32]int32{
+	packet.LevelEventParticlesEvaporateWater:      packet.LevelEventParticleLegacyEvent | 6,
+	packet.LevelEventParticlesDestroyBlockNoSound: packet.LevelEventParticlesDestroyBlock,
+	packet.LevelEventParticlesExplosion:           packet.LevelEventParticleLegacyEvent | 14,
+	packet.LevelEventParticlesBlockExplosion:      packet.LevelEventParticleLegacyEvent | 14,
+	packet.LevelEventCauldronFillLava:             packet.LevelEventCauldronFillWater,
+	packet.LevelEventCauldronTakeLava:             packet.LevelEventCauldronTakeWater,
+	packet.LevelEventCauldronFillPowderSnow:       packet.LevelEventCauldronFillWater,
+	packet.LevelEventCauldronTakePowderSnow:       packet.LevelEventCauldronTakeWater,
+	packet.LevelEventParticlesShootWhiteSmoke:     packet.LevelEventParticlesShoot,
+	packet.LevelEventParticlesBreezeWindExplosion: packet.LevelEventParticleLegacyEvent | 5,
+	packet.LevelEventParticlesWindExplosion:       packet.LevelEventParticleLegacyEvent | 5,
+}
+
+// legacyParticles maps the IDs of particles in the latest version, sent using packet.LevelEventParticleLegacyEvent,
+// to the ID of the same or a similar particle in v1.12.0. Particles not present here cannot be shown by v1.12.0
+// clients.
+var legacyParticles = map[int32]int32{
+	1:  1,  // Bubble.
+	2:  1,  // Manual bubble.
+	3:  2,  // Critical.
+	4:  3,  // Block force field.
+	5:  4,  // Smoke.
+	6:  5,  // Explode.
+	7:  6,  // Evaporation.
+	8:  7,  // Flame.
+	9:  7,  // Candle flame.
+	10: 8,  // Lava.
+	11: 9,  // Large smoke.
+	12: 10, // Redstone.
+	13: 11, // Rising red dust.
+	14: legacyParticleItemBreak,
+	15: 13, // Snowball poof.
+	16: 14, // Huge explode.
+	17: 15, // Huge explode seed.
+	18: 16, // Mob flame.
+	19: 17, // Heart.
+	20: legacyParticleTerrain,
+	21: 19, // Town aura.
+	22: 20, // Portal.
+	23: 20, // Portal.
+	24: 21, // Water splash.
+	25: 21, // Manual water splash.
+	26: 22, // Water wake.
+	27: 23, // Water drip.
+	28: 24, // Lava drip.
+	29: 24, // Honey drip.
+	30: 23, // Stalactite water drip.
+	31: 24, // Stalactite lava drip.
+	32: 25, // Falling dust.
+	33: 26, // Mob spell.
+	34: 27, // Ambient mob spell.
+	35: 28, // Instantaneous mob spell.
+	36: 29, // Ink.
+	37: 30, // Slime.
+	38: 31, // Rain splash.
+	39: 32, // Angry villager.
+	40: 33, // Happy villager.
+	41: 34, // Enchantment table.
+	42: 35, // Tracking emitter.
+	43: 36, // Note.
+	44: 37, // Witch spell.
+	45: 38, // Carrot.
+	46: 39, // Mob appearance.
+	47: 40, // End rod.
+	48: 41, // Dragon's breath.
+	49: 42, // Spit.
+	50: 43, // Totem.
+	51: 44, // Food.
+	52: 45, // Fireworks starter.
+	53: 46, // Fireworks spark.
+	54: 47, // Fireworks overlay.
+	55: 48, // Balloon gas.
+	56: 49, // Coloured flame.
+	57: 50, // Sparkler.
+	58: 51, // Conduit.
+	59: 52, // Bubble column up.
+	60: 53, // Bubble column down.
+	61: 54, // Sneeze.
+	64: 5,  // Dragon destroy block.
+	65: 19, // Mycelium dust.
+	66: 25, // Falling border dust.
+	67: 9,  // Campfire smoke.
+	68: 9,  // Tall campfire smoke.
+	69: 41, // Dragon breath fire.
+	70: 41, // Dragon breath trail.
+	71: 7,  // Blue flame.
+	74: 20, // Reverse portal.
+	75: 13, // Snowflake.
+	77: 10, // Sculk sensor redstone.
+	80: 33, // Wax.
+}
+
+// particleNames maps the names of built-in particle effects added after v1.12.0 to the name of a similar particle
+// effect that v1.12.0 clients know, or to an empty string if there is none. Names not present here are sent as is.
+var particleNames = map[string]string{
+	"minecraft:blue_flame_particle":             "minecraft:basic_flame_particle",
+	"minecraft:candle_flame_particle":           "minecraft:basic_flame_particle",
+	"minecraft:campfire_smoke_particle":         "minecraft:basic_smoke_particle",
+	"minecraft:campfire_tall_smoke_particle":    "minecraft:basic_smoke_particle",
+	"minecraft:honey_drip_particle":             "minecraft:lava_drip_particle",
+	"minecraft:stalactite_lava_drip_particle":   "minecraft:lava_drip_particle",
+	"minecraft:stalactite_water_drip_particle":  "minecraft:water_drip_particle",
+	"minecraft:cherry_leaves_particle":          "",
+	"minecraft:dust_plume":                      "",
+	"minecraft:electric_spark_particle":         "",
+	"minecraft:falling_border_dust_particle":    "",
+	"minecraft:nectar_drip_particle":            "",
+	"minecraft:obsidian_glow_dust_particle":     "",
+	"minecraft:sculk_charge_particle":           "",
+	"minecraft:sculk_charge_pop_particle":       "",
+	"minecraft:sculk_sensor_redstone_particle":  "",
+	"minecraft:sculk_soul_particle":             "",
+	"minecraft:shriek_particle":                 "",
+	"minecraft:snowflake_particle":              "",
+	"minecraft:sonic_explosion":                 "",
+	"minecraft:soul_particle":                   "",
+	"minecraft:spore_blossom_ambient_particle":  "",
+	"minecraft:spore_blossom_shower_particle":   "",
+	"minecraft:vibration_signal":                "",
+	"minecraft:wax_particle":                    "",
+	"minecraft:wind_explosion_emitter":          "",
+	"minecraft:breeze_wind_explosion_emitter":   "",
+	"minecraft:trial_spawner_detection":         "",
+	"minecraft:trial_spawner_detection_ominous": "",
+	"minecraft:vault_connection_particle":       "",
+}
+
+// downgradeLevelEvent downgrades a LevelEvent packet to v1.12.0. Level events and particles added after v1.12.0 are
+// replaced by a similar one, and block and item runtime IDs held in the event data are downgraded. False is returned
+// if the event cannot be shown by v1.12.0 clients.
+func (s *state) downgradeLevelEvent(pk *packet.LevelEvent) (*packet.LevelEvent, bool) {
+	eventType, data := pk.EventType, pk.EventData
+	if eventType&packet.LevelEventParticleLegacyEvent != 0 {
+		particle, ok := legacyParticles[eventType&^packet.LevelEventParticleLegacyEvent]
+		if !ok {
+			return nil, false
+		}
+		switch particle {
+		case legacyParticleTerrain:
+			data = int32(s.downgradeBlockRuntimeID(uint32(data)))
+		case legacyParticleItemBreak:
+			data = downgradeItemEventData(s.mappings(), data)
+		}
+		return &packet.LevelEvent{EventType: packet.LevelEventParticleLegacyEvent | particle, Position: pk.Position, EventData: data}, true
+	}
+	if eventType >= packet.LevelEventParticlesCrackBlockDown && eventType <= packet.LevelEventParticlesCrackBlockEast {
+		// v1.12.0 has a single event for cracking blocks, which holds the face in the highest byte of the data.
+		data |= (eventType - packet.LevelEventParticlesCrackBlockDown) << 24
+		eventType = packet.LevelEventParticlesCrackBlock
+	}
+	if sub, ok := levelEventSubstitutes[eventType]; ok {
+		eventType = sub
+	} else if _, ok := legacyLevelEvents[eventType]; !ok {
+		return nil, false
+	}
+	switch eventType {
+	case packet.LevelEventParticlesDestroyBlock:
+		data = int32(s.downgradeBlockRuntimeID(uint32(data)))
+	case packet.LevelEventParticlesCrackBlock:
+		data = int32(s.downgradeBlockRuntimeID(uint32(data)&0xffffff)) | data&^0xffffff
+	}
+	return &packet.LevelEvent{EventType: eventType, Position: pk.Position, EventData: data}, true
+}
+
+// downgradeParticleEffect downgrades a SpawnParticleEffect packet to v1.12.0. False is returned if the particle
+// effect was added after v1.12.0 and has no similar particle effect.
+func downgradeParticleEffect(pk *packet.SpawnParticleEffect) (*legacypacket.SpawnParticleEffect, bool) {
+	name := pk.ParticleName
+	if sub, ok := particleNames[name]; ok {
+		if sub == "" {
+			return nil, false
+		}
+		name = sub
+	}
+	return &legacypacket.SpawnParticleEffect{
+		Dimension:    pk.Dimension,
+		Position:     pk.Position,
+		ParticleName: name,
+	}, true
+}
+
+// downgradeItemEventData downgrades the event data of an item break particle, which holds the runtime ID of an item
+// in the upper 16 bits and its metadata value in the lower 16 bits.
+func downgradeItemEventData(m *latestmappings.Mappings, data int32) int32 {
+	name, _ := m.ItemRuntimeIDToName(data >> 16)
+	id, meta, _ := legacymappings.ItemByName(name, int16(data&0xffff))
+	return int32(id)<<16 | int32(uint16(meta))
+}
@@ -0,0 +1,99 @@
+package tedac
+
+import (
+	"github.com/didntpot/tedac/tedac/latestmappings"
+	"github.com/didntpot/tedac/tedac/legacyprotocol/legacypacket"
+	"github.com/sandertv/gophertunnel/minecraft/protocol/packet"
+	"testing"
+)
+
+func TestDowngradeLevelEvent(t *testing.T) {
+	airRID, _ := latestmappings.StateToRuntimeID("minecraft:air", nil)
+	tests := []struct {
+		name string
+		pk   *packet.LevelEvent
+		want *packet.LevelEvent
+	}{
+		{
+			name: "unchanged",
+			pk:   &packet.LevelEvent{EventType: packet.LevelEventSoundClick},
+			want: &packet.LevelEvent{EventType: packet.LevelEventSoundClick},
+		},
+		{
+			name: "substituted",
+			pk:   &packet.LevelEvent{EventType: packet.LevelEventCauldronFillLava},
+			want: &packet.LevelEvent{EventType: packet.LevelEventCauldronFillWater},
+		},
+		{
+			name: "legacy particle",
+			pk:   &packet.LevelEvent{EventType: packet.LevelEventParticleLegacyEvent | 9},
+			want: &packet.LevelEvent{EventType: packet.LevelEventParticleLegacyEvent | 7},
+		},
+		{
+			name: "unknown legacy particle",
+			pk:   &packet.LevelEvent{EventType: packet.LevelEventParticleLegacyEvent | 62},
+		},
+		{
+			name: "terrain particle",
+			pk:   &packet.LevelEvent{EventType: packet.LevelEventParticleLegacyEvent | 20, EventData: int32(airRID)},
+			want: &packet.LevelEvent{EventType: packet.LevelEventParticleLegacyEvent | legacyParticleTerrain, EventData: int32(legacyAirRID)},
+		},
+		{
+			name: "crack block down",
+			pk:   &packet.LevelEvent{EventType: packet.LevelEventParticlesCrackBlockDown, EventData: int32(airRID)},
+			want: &packet.LevelEvent{EventType: packet.LevelEventParticlesCrackBlock, EventData: int32(legacyAirRID)},
+		},
+		{
+			name: "crack block east",
+			pk:   &packet.LevelEvent{EventType: packet.LevelEventParticlesCrackBlockEast, EventData: int32(airRID)},
+			want: &packet.LevelEvent{EventType: packet.LevelEventParticlesCrackBlock, EventData: int32(legacyAirRID) | 5<<24},
+		},
+		{
+			name: "dropped",
+			pk:   &packet.LevelEvent{EventType: packet.LevelEventSoundPointedDripstoneLand},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := &state{}
+			got, ok := s.downgradeLevelEvent(test.pk)
+			if test.want == nil {
+				if ok {
+					t.Fatalf("got %#v, want event to be dropped", got)
+				}
+				return
+			}
+			if !ok {
+				t.Fatalf("event was dropped, want %#v", test.want)
+			}
+			if *got != *test.want {
+				t.Errorf("got %#v, want %#v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestDowngradeParticleEffect(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{name: "minecraft:basic_flame_particle", want: "minecraft:basic_flame_particle"},
+		{name: "minecraft:blue_flame_particle", want: "minecraft:basic_flame_particle"},
+		{name: "minecraft:sonic_explosion"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, ok := downgradeParticleEffect(&packet.SpawnParticleEffect{ParticleName: test.name})
+			if test.want == "" {
+				if ok {
+					t.Fatalf("got %#v, want particle effect to be dropped", got)
+				}
+				return
+			}
+			if want := (legacypacket.SpawnParticleEffect{ParticleName: test.want}); !ok || *got != want {
+				t.Errorf("got %#v, want %#v", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package tedac
+
+import (
+	"github.com/didntpot/tedac/tedac/latestmappings"
+	"github.com/didntpot/tedac/tedac/legacymappings"
+	"github.com/sandertv/gophertunnel/minecraft"
+)
+
+// customBlockFallbacks holds the vanilla blocks shown to a v1.12.0 client instead of the custom blocks of the server.
+type customBlockFallbacks struct {
+	names    map[string]string
+	fallback string
+}
+
+// SetMappings sets the Mappings of the server the connection passed is connected to, so that the runtime IDs of
+// blocks and items sent by that server are translated correctly. The Mappings are set when the game is started for
+// the connection, but must be set again if the connection is moved to another server without starting the game
+// again.
+func SetMappings(conn *minecraft.Conn, m *latestmappings.Mappings) {
+	stateOf(conn).latestMappings.Store(m)
+}
+
+// SetCustomBlockFallbacks sets the vanilla blocks shown to the v1.12.0 client of the connection passed instead of
+// the custom blocks of the server. The names map holds the name of the vanilla block in the latest version to show
+// for every custom block. Custom blocks not present are shown as the fallback block, or as an update block if the
+// fallback is empty.
+func SetCustomBlockFallbacks(conn *minecraft.Conn, names map[string]string, fallback string) {
+	stateOf(conn).customBlockFallbacks.Store(customBlockFallbacks{names: names, fallback: fallback})
+}
+
+// MappingsOf returns the Mappings of the server the connection passed is connected to.
+func MappingsOf(conn *minecraft.Conn) *latestmappings.Mappings {
+	return stateOf(conn).mappings()
+}
+
+// mappings returns the Mappings of the server the client is connected to.
+func (s *state) mappings() *latestmappings.Mappings {
+	if m := s.latestMappings.Load(); m != nil {
+		return m
+	}
+	return latestmappings.Vanilla()
+}
+
+// downgradeBlockRuntimeID downgrades the latest block runtime ID to a v1.12.0 block runtime ID.
+func (s *state) downgradeBlockRuntimeID(input uint32) uint32 {
+	m := s.mappings()
+	name, properties, ok := m.RuntimeIDToState(input)
+	if !ok {
+		return legacyAirRID
+	}
+	if m.CustomBlock(name) {
+		fallbacks := s.customBlockFallbacks.Load()
+		name, properties = fallbacks.names[name], nil
+		if name == "" {
+			name = fallbacks.fallback
+		}
+	}
+	return legacymappings.StateToRuntimeID(name, properties)
+}
+
+// upgradeBlockRuntimeID upgrades a v1.12.0 block runtime ID to the latest block runtime ID.
+func (s *state) upgradeBlockRuntimeID(input uint32) uint32 {
+	m := s.mappings()
+	name, properties, ok := legacymappings.RuntimeIDToState(input)
+	if !ok {
+		return m.AirRuntimeID()
+	}
+	runtimeID, ok := m.StateToRuntimeID(name, properties)
+	if !ok {
+		return m.AirRuntimeID()
+	}
+	return runtimeID
+}
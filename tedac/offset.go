@@ -0,0 +1,116 @@
+package tedac
+
+import (
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/sandertv/gophertunnel/minecraft"
+	"github.com/sandertv/gophertunnel/minecraft/protocol"
+	"github.com/sandertv/gophertunnel/minecraft/protocol/packet"
+)
+
+// SetVerticalOffset sets the vertical offset of the 256 block high window of the Overworld that the v1.12.0 client
+// of the connection passed sees. A block at Y=offset in the latest version is shown at Y=0 to the client, so an
+// offset of -64 makes the blocks between -64 and 191 visible. The offset is rounded down to a multiple of 16 and
+// clamped so that the window stays within the Overworld. SetVerticalOffset must be called before the game is
+// started for the connection.
+func SetVerticalOffset(conn *minecraft.Conn, offset int32) {
+	r := DimensionRange(packet.DimensionOverworld)
+	offset = max(min(offset&^15, int32(r[1]+1-256)), int32(r[0]))
+	stateOf(conn).verticalOffset.Store(offset)
+}
+
+// offset returns the vertical offset that applies to the dimension the client is currently in. Only the
+// Overworld is higher than 256 blocks, so the other dimensions always have an offset of 0.
+func (s *state) offset() int32 {
+	if s.dimension.Load() != packet.DimensionOverworld {
+		return 0
+	}
+	return s.verticalOffset.Load()
+}
+
+// shiftPositions shifts all positions in a packet of the latest version vertically by delta. It is called with the
+// negative offset for packets sent to the client, and with the positive offset for packets sent by the client.
+// The delta is always a multiple of 16, so sub chunk positions are shifted by delta/16.
+func shiftPositions(pk packet.Packet, delta int32) {
+	vec := func(v *mgl32.Vec3) {
+		v[1] += float32(delta)
+	}
+	pos := func(p *protocol.BlockPos) {
+		p[1] += delta
+	}
+	switch pk := pk.(type) {
+	case *packet.StartGame:
+		vec(&pk.PlayerPosition)
+		pos(&pk.WorldSpawn)
+	case *packet.MovePlayer:
+		vec(&pk.Position)
+	case *packet.MoveActorAbsolute:
+		vec(&pk.Position)
+	case *packet.MoveActorDelta:
+		vec(&pk.Position)
+	case *packet.AddActor:
+		vec(&pk.Position)
+	case *packet.AddPlayer:
+		vec(&pk.Position)
+	case *packet.AddItemActor:
+		vec(&pk.Position)
+	case *packet.AddPainting:
+		vec(&pk.Position)
+	case *packet.ChangeDimension:
+		vec(&pk.Position)
+	case *packet.Respawn:
+		vec(&pk.Position)
+	case *packet.SetSpawnPosition:
+		pos(&pk.Position)
+		pos(&pk.SpawnPosition)
+	case *packet.NetworkChunkPublisherUpdate:
+		pos(&pk.Position)
+	case *packet.UpdateBlock:
+		pos(&pk.Position)
+	case *packet.UpdateBlockSynced:
+		pos(&pk.Position)
+	case *packet.UpdateSubChunkBlocks:
+		pk.Position[1] += delta >> 4
+		for i := range pk.Blocks {
+			pos(&pk.Blocks[i].BlockPos)
+		}
+		for i := range pk.Extra {
+			pos(&pk.Extra[i].BlockPos)
+		}
+	case *packet.AddVolumeEntity:
+		pos(&pk.Bounds[0])
+		pos(&pk.Bounds[1])
+	case *packet.SpawnExperienceOrb:
+		vec(&pk.Position)
+	case *packet.BlockEvent:
+		pos(&pk.Position)
+	case *packet.BlockActorData:
+		pos(&pk.Position)
+		if y, ok := pk.NBTData["y"].(int32); ok {
+			pk.NBTData["y"] = y + delta
+		}
+	case *packet.ContainerOpen:
+		pos(&pk.ContainerPosition)
+	case *packet.LevelEvent:
+		vec(&pk.Position)
+	case *packet.LevelSoundEvent:
+		vec(&pk.Position)
+	case *packet.SpawnParticleEffect:
+		vec(&pk.Position)
+	case *packet.PlaySound:
+		vec(&pk.Position)
+	case *packet.PlayerAction:
+		pos(&pk.BlockPosition)
+	case *packet.BlockPickRequest:
+		pos(&pk.Position)
+	case *packet.InventoryTransaction:
+		switch data := pk.TransactionData.(type) {
+		case *protocol.UseItemTransactionData:
+			pos(&data.BlockPosition)
+			vec(&data.Position)
+		case *protocol.UseItemOnEntityTransactionData:
+			vec(&data.Position)
+		case *protocol.ReleaseItemTransactionData:
+			vec(&data.HeadPosition)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package tedac
+
+import (
+	"github.com/didntpot/tedac/tedac/legacyprotocol/legacypacket"
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/sandertv/gophertunnel/minecraft"
+	"github.com/sandertv/gophertunnel/minecraft/protocol/packet"
+	"testing"
+)
+
+func TestSetVerticalOffset(t *testing.T) {
+	tests := []struct {
+		offset, want int32
+	}{
+		{offset: 0, want: 0},
+		{offset: 7, want: 0},
+		{offset: -17, want: -32},
+		{offset: -64, want: -64},
+		{offset: -100, want: -64},
+		{offset: 64, want: 64},
+		{offset: 100, want: 64},
+	}
+	for _, test := range tests {
+		conn := &minecraft.Conn{}
+		SetVerticalOffset(conn, test.offset)
+		if got := stateOf(conn).verticalOffset.Load(); got != test.want {
+			t.Errorf("SetVerticalOffset(%v): got offset %v, want %v", test.offset, got, test.want)
+		}
+		Release(conn)
+	}
+}
+
+func TestConvertFromLatestOffset(t *testing.T) {
+	tests := []struct {
+		name         string
+		from         int32
+		pk           packet.Packet
+		wantPosition mgl32.Vec3
+	}{
+		{
+			name:         "to overworld",
+			from:         packet.DimensionNether,
+			pk:           &packet.ChangeDimension{Dimension: packet.DimensionOverworld, Position: mgl32.Vec3{0, 0, 0}},
+			wantPosition: mgl32.Vec3{0, 64, 0},
+		},
+		{
+			name:         "to nether",
+			from:         packet.DimensionOverworld,
+			pk:           &packet.ChangeDimension{Dimension: packet.DimensionNether, Position: mgl32.Vec3{0, 0, 0}},
+			wantPosition: mgl32.Vec3{0, 0, 0},
+		},
+		{
+			name:         "start in overworld",
+			from:         packet.DimensionNether,
+			pk:           &packet.StartGame{Dimension: packet.DimensionOverworld, PlayerPosition: mgl32.Vec3{0, -64, 0}},
+			wantPosition: mgl32.Vec3{0, 0, 0},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			conn := &minecraft.Conn{}
+			defer Release(conn)
+			SetVerticalOffset(conn, -64)
+			stateOf(conn).dimension.Store(test.from)
+
+			pks := Protocol{}.ConvertFromLatest(test.pk, conn)
+			if len(pks) == 0 {
+				t.Fatalf("no packets returned")
+			}
+			var got mgl32.Vec3
+			switch pk := pks[0].(type) {
+			case *legacypacket.ChangeDimension:
+				got = pk.Position
+			case *legacypacket.StartGame:
+				got = pk.PlayerPosition
+			default:
+				t.Fatalf("unexpected packet %T", pk)
+			}
+			if got != test.wantPosition {
+				t.Errorf("got position %v, want %v", got, test.wantPosition)
+			}
+		})
+	}
+}
@@ -2,9 +2,8 @@ package tedac
 
 import (
 	"bytes"
-	"encoding/json"
 	"fmt"
-	"github.com/df-mc/dragonfly/server/world"
+	"github.com/df-mc/dragonfly/server/block/cube"
 	"github.com/didntpot/tedac/tedac/chunk"
 	"github.com/didntpot/tedac/tedac/latestmappings"
 	"github.com/didntpot/tedac/tedac/legacychunk"
@@ -15,6 +14,8 @@ import (
 	"github.com/sandertv/gophertunnel/minecraft"
 	"github.com/sandertv/gophertunnel/minecraft/protocol"
 	"github.com/sandertv/gophertunnel/minecraft/protocol/packet"
+	"maps"
+	"strings"
 
 	_ "github.com/didntpot/tedac/tedac/raknet"
 )
@@ -39,6 +40,7 @@ func (Protocol) Packets(bool) packet.Pool {
 		pool[k] = v
 	}
 	pool[packet.IDCommandRequest] = func() packet.Packet { return &legacypacket.CommandRequest{} }
+	pool[packet.IDCommandOutput] = func() packet.Packet { return &legacypacket.CommandOutput{} }
 	pool[packet.IDContainerClose] = func() packet.Packet { return &legacypacket.ContainerClose{} }
 	pool[packet.IDInventoryTransaction] = func() packet.Packet { return &legacypacket.InventoryTransaction{} }
 	pool[packet.IDMobEquipment] = func() packet.Packet { return &legacypacket.MobEquipment{} }
@@ -50,6 +52,7 @@ func (Protocol) Packets(bool) packet.Pool {
 	pool[packet.IDText] = func() packet.Packet { return &legacypacket.Text{} }
 	pool[packet.IDStopSound] = func() packet.Packet { return &legacypacket.StopSound{} }
 	pool[packet.IDSetTitle] = func() packet.Packet { return &legacypacket.SetTitle{} }
+	pool[packet.IDSpawnParticleEffect] = func() packet.Packet { return &legacypacket.SpawnParticleEffect{} }
 	return pool
 }
 
@@ -72,8 +75,20 @@ func (Protocol) Encryption(key [32]byte) packet.Encryption {
 var nullBytes = []byte("null\n")
 
 // ConvertToLatest ...
-func (Protocol) ConvertToLatest(pk packet.Packet, _ *minecraft.Conn) []packet.Packet {
+func (p Protocol) ConvertToLatest(pk packet.Packet, conn *minecraft.Conn) []packet.Packet {
+	pks := p.convertToLatest(pk, conn)
+	if offset := stateOf(conn).offset(); offset != 0 {
+		for _, pk := range pks {
+			shiftPositions(pk, offset)
+		}
+	}
+	return pks
+}
+
+// convertToLatest converts a packet sent by the v1.12.0 client to the latest version.
+func (Protocol) convertToLatest(pk packet.Packet, conn *minecraft.Conn) []packet.Packet {
 	// fmt.Printf("1.12 -> Latest: %T\n", pk)
+	m := stateOf(conn).mappings()
 	switch pk := pk.(type) {
 	case *legacypacket.SetTitle:
 		return []packet.Packet{
@@ -141,15 +156,26 @@ func (Protocol) ConvertToLatest(pk packet.Packet, _ *minecraft.Conn) []packet.Pa
 			},
 		}
 	case *legacypacket.InventoryTransaction:
+		if _, ok := pk.TransactionData.(*legacyprotocol.NormalTransactionData); ok {
+			// The latest version no longer accepts normal transactions, so we try to turn them into an item stack
+			// request instead.
+			if request, ok := stateOf(conn).inventory.request(m, pk.Actions); ok {
+				return []packet.Packet{
+					&packet.ItemStackRequest{Requests: []protocol.ItemStackRequest{request}},
+				}
+			}
+		}
+		inv := stateOf(conn).inventory
+		oldItems, newItems := inv.originalActions(m, pk.Actions)
 		actions := make([]protocol.InventoryAction, 0, len(pk.Actions))
-		for _, action := range pk.Actions {
+		for i, action := range pk.Actions {
 			actions = append(actions, protocol.InventoryAction{
 				SourceType:    action.SourceType,
 				WindowID:      action.WindowID,
 				SourceFlags:   action.SourceFlags,
 				InventorySlot: action.InventorySlot,
-				OldItem:       protocol.ItemInstance{Stack: upgradeItem(action.OldItem)},
-				NewItem:       protocol.ItemInstance{Stack: upgradeItem(action.NewItem)},
+				OldItem:       protocol.ItemInstance{Stack: oldItems[i]},
+				NewItem:       protocol.ItemInstance{Stack: newItems[i]},
 			})
 		}
 
@@ -160,30 +186,33 @@ func (Protocol) ConvertToLatest(pk packet.Packet, _ *minecraft.Conn) []packet.Pa
 		case *legacyprotocol.MismatchTransactionData:
 			transactionData = &protocol.MismatchTransactionData{}
 		case *legacyprotocol.UseItemTransactionData:
+			heldItem := inv.original(m, legacyprotocol.WindowIDInventory, uint32(data.HotBarSlot), data.HeldItem)
 			transactionData = &protocol.UseItemTransactionData{
 				ActionType:      data.ActionType,
 				BlockPosition:   data.BlockPosition,
 				BlockFace:       data.BlockFace,
 				HotBarSlot:      data.HotBarSlot,
-				HeldItem:        protocol.ItemInstance{Stack: upgradeItem(data.HeldItem)},
+				HeldItem:        protocol.ItemInstance{Stack: heldItem},
 				Position:        data.Position,
 				ClickedPosition: data.ClickedPosition,
-				BlockRuntimeID:  upgradeBlockRuntimeID(data.BlockRuntimeID),
+				BlockRuntimeID:  stateOf(conn).upgradeBlockRuntimeID(data.BlockRuntimeID),
 			}
 		case *legacyprotocol.UseItemOnEntityTransactionData:
+			heldItem := inv.original(m, legacyprotocol.WindowIDInventory, uint32(data.HotBarSlot), data.HeldItem)
 			transactionData = &protocol.UseItemOnEntityTransactionData{
 				TargetEntityRuntimeID: data.TargetEntityRuntimeID,
 				ActionType:            data.ActionType,
 				HotBarSlot:            data.HotBarSlot,
-				HeldItem:              protocol.ItemInstance{Stack: upgradeItem(data.HeldItem)},
+				HeldItem:              protocol.ItemInstance{Stack: heldItem},
 				Position:              data.Position,
 				ClickedPosition:       data.ClickedPosition,
 			}
 		case *legacyprotocol.ReleaseItemTransactionData:
+			heldItem := inv.original(m, legacyprotocol.WindowIDInventory, uint32(data.HotBarSlot), data.HeldItem)
 			transactionData = &protocol.ReleaseItemTransactionData{
 				ActionType:   data.ActionType,
 				HotBarSlot:   data.HotBarSlot,
-				HeldItem:     protocol.ItemInstance{Stack: upgradeItem(data.HeldItem)},
+				HeldItem:     protocol.ItemInstance{Stack: heldItem},
 				HeadPosition: data.HeadPosition,
 			}
 		}
@@ -212,16 +241,18 @@ func (Protocol) ConvertToLatest(pk packet.Packet, _ *minecraft.Conn) []packet.Pa
 			},
 		}
 	case *legacypacket.MobEquipment:
+		item := stateOf(conn).inventory.original(m, uint32(pk.WindowID), uint32(pk.InventorySlot), pk.NewItem)
 		return []packet.Packet{
 			&packet.MobEquipment{
 				EntityRuntimeID: pk.EntityRuntimeID,
-				NewItem:         protocol.ItemInstance{Stack: upgradeItem(pk.NewItem)},
+				NewItem:         protocol.ItemInstance{Stack: item},
 				InventorySlot:   pk.InventorySlot,
 				HotBarSlot:      pk.HotBarSlot,
 				WindowID:        pk.WindowID,
 			},
 		}
 	case *legacypacket.ContainerClose:
+		stateOf(conn).inventory.close(pk.WindowID)
 		return []packet.Packet{
 			&packet.ContainerClose{
 				WindowID:   pk.WindowID,
@@ -236,6 +267,9 @@ func (Protocol) ConvertToLatest(pk packet.Packet, _ *minecraft.Conn) []packet.Pa
 				Internal:      pk.Internal,
 			},
 		}
+	case *packet.BlockActorData:
+		pk.NBTData = upgradeBlockEntity(pk.NBTData)
+		return []packet.Packet{pk}
 	case *packet.AdventureSettings:
 		// TODO: Send request ability instead?
 		return nil
@@ -250,6 +284,18 @@ func (Protocol) ConvertToLatest(pk packet.Packet, _ *minecraft.Conn) []packet.Pa
 // ConvertFromLatest ...
 func (Protocol) ConvertFromLatest(pk packet.Packet, conn *minecraft.Conn) []packet.Packet {
 	// fmt.Printf("Latest -> 1.12: %T\n", pk)
+	// The dimension must be stored before the offset is computed, as the positions in these packets already belong
+	// to the new dimension.
+	switch pk := pk.(type) {
+	case *packet.ChangeDimension:
+		stateOf(conn).dimension.Store(pk.Dimension)
+	case *packet.StartGame:
+		stateOf(conn).dimension.Store(pk.Dimension)
+	}
+	if offset := stateOf(conn).offset(); offset != 0 {
+		shiftPositions(pk, -offset)
+	}
+	m := stateOf(conn).mappings()
 	switch pk := pk.(type) {
 	case *packet.RequestNetworkSettings:
 		return []packet.Packet{
@@ -262,6 +308,14 @@ func (Protocol) ConvertFromLatest(pk packet.Packet, conn *minecraft.Conn) []pack
 				Port:    pk.Port,
 			},
 		}
+	case *packet.ChangeDimension:
+		return []packet.Packet{
+			&legacypacket.ChangeDimension{
+				Dimension: pk.Dimension,
+				Position:  pk.Position,
+				Respawn:   pk.Respawn,
+			},
+		}
 	case *packet.SetTitle:
 		return []packet.Packet{
 			&legacypacket.SetTitle{
@@ -305,6 +359,7 @@ func (Protocol) ConvertFromLatest(pk packet.Packet, conn *minecraft.Conn) []pack
 			},
 		}
 	case *packet.StartGame:
+		SetMappings(conn, latestmappings.New(pk.Blocks, pk.UseBlockNetworkIDHashes, pk.Items))
 		return []packet.Packet{
 			&legacypacket.StartGame{
 				EntityUniqueID:                 pk.EntityUniqueID,
@@ -359,13 +414,16 @@ func (Protocol) ConvertFromLatest(pk packet.Packet, conn *minecraft.Conn) []pack
 	case *packet.LevelChunk:
 		buf := bytes.NewBuffer(pk.RawPayload)
 		oldFormat := conn.GameData().BaseGameVersion == "1.17.40"
-		c, err := chunk.NetworkDecode(latestAirRID, buf, int(pk.SubChunkCount), oldFormat, world.Overworld.Range())
+		dimension := stateOf(conn).dimension.Load()
+		c, err := chunk.NetworkDecode(m.AirRuntimeID(), buf, int(pk.SubChunkCount), oldFormat, DimensionRange(dimension))
 		if err != nil {
 			fmt.Println(err)
 			return nil
 		}
 
-		writeBuf, data := bytes.NewBuffer(nil), legacychunk.Encode(downgradeChunk(c), legacychunk.NetworkEncoding)
+		r, offset := legacychunk.DimensionRange(dimension), stateOf(conn).offset()
+		downgraded := stateOf(conn).downgradeChunk(c, r, offset)
+		writeBuf, data := bytes.NewBuffer(nil), legacychunk.Encode(downgraded, legacychunk.NetworkEncoding)
 		for i := range data.SubChunks {
 			_, _ = writeBuf.Write(data.SubChunks[i])
 		}
@@ -376,7 +434,7 @@ func (Protocol) ConvertFromLatest(pk packet.Packet, conn *minecraft.Conn) []pack
 				BlobHashes:    pk.BlobHashes,
 				CacheEnabled:  pk.CacheEnabled,
 				Position:      pk.Position,
-				RawPayload:    append(writeBuf.Bytes(), buf.Bytes()...),
+				RawPayload:    append(writeBuf.Bytes(), downgradeBlockEntities(buf.Bytes(), r, offset)...),
 				SubChunkCount: uint32(len(data.SubChunks)),
 			},
 		}
@@ -388,10 +446,16 @@ func (Protocol) ConvertFromLatest(pk packet.Packet, conn *minecraft.Conn) []pack
 				}),
 			},
 		}
+	case *packet.BlockActorData:
+		data, ok := downgradeBlockEntity(pk.NBTData)
+		if !ok {
+			return nil
+		}
+		pk.NBTData = data
 	case *packet.UpdateBlock:
-		pk.NewBlockRuntimeID = downgradeBlockRuntimeID(pk.NewBlockRuntimeID)
+		pk.NewBlockRuntimeID = stateOf(conn).downgradeBlockRuntimeID(pk.NewBlockRuntimeID)
 	case *packet.UpdateBlockSynced:
-		pk.NewBlockRuntimeID = downgradeBlockRuntimeID(pk.NewBlockRuntimeID)
+		pk.NewBlockRuntimeID = stateOf(conn).downgradeBlockRuntimeID(pk.NewBlockRuntimeID)
 	case *packet.NetworkChunkPublisherUpdate:
 		return []packet.Packet{
 			&legacypacket.NetworkChunkPublisherUpdate{
@@ -421,11 +485,17 @@ func (Protocol) ConvertFromLatest(pk packet.Packet, conn *minecraft.Conn) []pack
 			},
 		}
 	case *packet.AddActor:
+		entityType, metadata, ok := downgradeEntity(pk.EntityType, pk.EntityMetadata)
+		if !ok {
+			return nil
+		}
+		s := stateOf(conn)
+		s.entities.add(pk.EntityUniqueID, pk.EntityRuntimeID, pk.EntityType)
 		return []packet.Packet{
 			&legacypacket.AddActor{
-				EntityMetadata:  legacyprotocol.DowngradeEntityMetadata(pk.EntityMetadata),
+				EntityMetadata:  s.downgradeEntityMetadata(pk.EntityType, metadata),
 				EntityRuntimeID: pk.EntityRuntimeID,
-				EntityType:      pk.EntityType,
+				EntityType:      entityType,
 				EntityUniqueID:  pk.EntityUniqueID,
 				HeadYaw:         pk.HeadYaw,
 				Pitch:           pk.Pitch,
@@ -463,8 +533,8 @@ func (Protocol) ConvertFromLatest(pk packet.Packet, conn *minecraft.Conn) []pack
 				Pitch:                  pk.Pitch,
 				Yaw:                    pk.Yaw,
 				HeadYaw:                pk.HeadYaw,
-				HeldItem:               downgradeItem(pk.HeldItem.Stack),
-				EntityMetadata:         legacyprotocol.DowngradeEntityMetadata(pk.EntityMetadata),
+				HeldItem:               downgradeItem(m, pk.HeldItem.Stack),
+				EntityMetadata:         stateOf(conn).downgradeEntityMetadata("minecraft:player", pk.EntityMetadata),
 				CommandPermissionLevel: uint32(pk.AbilityData.CommandPermissions),
 				PermissionLevel:        uint32(pk.AbilityData.PlayerPermissions),
 				DeviceID:               pk.DeviceID,
@@ -482,7 +552,7 @@ func (Protocol) ConvertFromLatest(pk packet.Packet, conn *minecraft.Conn) []pack
 		return []packet.Packet{
 			&legacypacket.MobEquipment{
 				EntityRuntimeID: pk.EntityRuntimeID,
-				NewItem:         downgradeItem(pk.NewItem.Stack),
+				NewItem:         downgradeItem(m, pk.NewItem.Stack),
 				InventorySlot:   pk.InventorySlot,
 				HotBarSlot:      pk.HotBarSlot,
 				WindowID:        pk.WindowID,
@@ -492,10 +562,10 @@ func (Protocol) ConvertFromLatest(pk packet.Packet, conn *minecraft.Conn) []pack
 		return []packet.Packet{
 			&legacypacket.MobArmourEquipment{
 				EntityRuntimeID: pk.EntityRuntimeID,
-				Helmet:          downgradeItem(pk.Helmet.Stack),
-				Chestplate:      downgradeItem(pk.Chestplate.Stack),
-				Leggings:        downgradeItem(pk.Leggings.Stack),
-				Boots:           downgradeItem(pk.Boots.Stack),
+				Helmet:          downgradeItem(m, pk.Helmet.Stack),
+				Chestplate:      downgradeItem(m, pk.Chestplate.Stack),
+				Leggings:        downgradeItem(m, pk.Leggings.Stack),
+				Boots:           downgradeItem(m, pk.Boots.Stack),
 			},
 		}
 	case *packet.AddItemActor:
@@ -503,14 +573,17 @@ func (Protocol) ConvertFromLatest(pk packet.Packet, conn *minecraft.Conn) []pack
 			&legacypacket.AddItemActor{
 				EntityUniqueID:  pk.EntityUniqueID,
 				EntityRuntimeID: pk.EntityRuntimeID,
-				Item:            downgradeItem(pk.Item.Stack),
+				Item:            downgradeItem(m, pk.Item.Stack),
 				Position:        pk.Position,
 				Velocity:        pk.Velocity,
-				EntityMetadata:  legacyprotocol.DowngradeEntityMetadata(pk.EntityMetadata),
+				EntityMetadata:  stateOf(conn).downgradeEntityMetadata("minecraft:item", pk.EntityMetadata),
 				FromFishing:     pk.FromFishing,
 			},
 		}
+	case *packet.ContainerOpen:
+		stateOf(conn).inventory.open(pk.WindowID, pk.ContainerType)
 	case *packet.ContainerClose:
+		stateOf(conn).inventory.close(pk.WindowID)
 		return []packet.Packet{
 			&legacypacket.ContainerClose{
 				WindowID: pk.WindowID,
@@ -521,21 +594,16 @@ func (Protocol) ConvertFromLatest(pk packet.Packet, conn *minecraft.Conn) []pack
 			&legacypacket.PlayerList{
 				ActionType: pk.ActionType,
 				Entries: lo.Map(pk.Entries, func(e protocol.PlayerListEntry, _ int) legacypacket.PlayerListEntry {
-					var patch struct {
-						Geometry struct {
-							Default string
-						}
-					}
-					_ = json.Unmarshal(e.Skin.SkinResourcePatch, &patch)
+					skin := downgradeSkin(e.Skin)
 					return legacypacket.PlayerListEntry{
 						UUID:             e.UUID,
 						EntityUniqueID:   e.EntityUniqueID,
 						Username:         e.Username,
 						SkinID:           e.Skin.SkinID,
-						SkinData:         e.Skin.SkinData,
-						CapeData:         e.Skin.CapeData,
-						SkinGeometryName: patch.Geometry.Default,
-						SkinGeometry:     e.Skin.SkinGeometry,
+						SkinData:         skin.data,
+						CapeData:         skin.capeData,
+						SkinGeometryName: skin.geometryName,
+						SkinGeometry:     skin.geometry,
 						PlatformChatID:   e.PlatformChatID,
 						XUID:             e.XUID,
 					}
@@ -608,26 +676,36 @@ func (Protocol) ConvertFromLatest(pk packet.Packet, conn *minecraft.Conn) []pack
 			},
 		}
 	case *packet.SetActorData:
+		s, metadata := stateOf(conn), pk.EntityMetadata
+		entityType, ok := s.entities.entityType(pk.EntityRuntimeID)
+		if ok {
+			// Apply the same adjustments made when the entity was added, such as its scale.
+			_, metadata, _ = downgradeEntity(entityType, metadata)
+		}
 		return []packet.Packet{
 			&legacypacket.SetActorData{
 				EntityRuntimeID: pk.EntityRuntimeID,
-				EntityMetadata:  legacyprotocol.DowngradeEntityMetadata(pk.EntityMetadata),
+				EntityMetadata:  s.downgradeEntityMetadata(entityType, metadata),
 			},
 		}
+	case *packet.RemoveActor:
+		stateOf(conn).entities.remove(pk.EntityUniqueID)
 	case *packet.InventorySlot:
+		stateOf(conn).inventory.setSlot(pk.WindowID, pk.Slot, pk.NewItem)
 		return []packet.Packet{
 			&legacypacket.InventorySlot{
 				WindowID: pk.WindowID,
 				Slot:     pk.Slot,
-				NewItem:  downgradeItem(pk.NewItem.Stack),
+				NewItem:  downgradeItem(m, pk.NewItem.Stack),
 			},
 		}
 	case *packet.InventoryContent:
+		stateOf(conn).inventory.setContent(pk.WindowID, pk.Content)
 		return []packet.Packet{
 			&legacypacket.InventoryContent{
 				WindowID: pk.WindowID,
 				Content: lo.Map(pk.Content, func(instance protocol.ItemInstance, _ int) legacyprotocol.ItemStack {
-					return downgradeItem(instance.Stack)
+					return downgradeItem(m, instance.Stack)
 				}),
 			},
 		}
@@ -668,65 +746,82 @@ func (Protocol) ConvertFromLatest(pk packet.Packet, conn *minecraft.Conn) []pack
 			},
 		}
 	case *packet.LevelEvent:
-		if pk.EventType == packet.LevelEventParticlesDestroyBlock || pk.EventType == packet.LevelEventParticlesCrackBlock {
-			pk.EventData = int32(downgradeBlockRuntimeID(uint32(pk.EventData)))
+		downgraded, ok := stateOf(conn).downgradeLevelEvent(pk)
+		if !ok {
+			return nil
+		}
+		return []packet.Packet{downgraded}
+	case *packet.SpawnParticleEffect:
+		downgraded, ok := downgradeParticleEffect(pk)
+		if !ok {
+			return nil
+		}
+		return []packet.Packet{downgraded}
+	case *packet.CommandOutput:
+		return []packet.Packet{
+			&legacypacket.CommandOutput{
+				CommandOrigin: legacyprotocol.CommandOrigin{
+					Origin:         pk.CommandOrigin.Origin,
+					UUID:           pk.CommandOrigin.UUID,
+					RequestID:      pk.CommandOrigin.RequestID,
+					PlayerUniqueID: pk.CommandOrigin.PlayerUniqueID,
+				},
+				OutputType:   pk.OutputType,
+				SuccessCount: pk.SuccessCount,
+				OutputMessages: lo.Map(pk.OutputMessages, func(m protocol.CommandOutputMessage, _ int) legacyprotocol.CommandOutputMessage {
+					return legacyprotocol.CommandOutputMessage{
+						Success:    m.Success,
+						Message:    m.Message,
+						Parameters: m.Parameters,
+					}
+				}),
+				DataSet: pk.DataSet,
+			},
 		}
 	case *packet.AvailableCommands:
+		commands := downgradeCommands(pk)
+		stateOf(conn).softEnums.Store(softEnumTypes(commands))
 		return []packet.Packet{
 			&legacypacket.AvailableCommands{
-				Commands: lo.Map(pk.Commands, func(c protocol.Command, _ int) legacyprotocol.Command {
-					return legacyprotocol.Command{
-						Name:            c.Name,
-						Description:     c.Description,
-						Flags:           byte(c.Flags),
-						PermissionLevel: c.PermissionLevel,
-						//Aliases:         c.Aliases,
-						Overloads: lo.Map(c.Overloads, func(o protocol.CommandOverload, i int) legacyprotocol.CommandOverload {
-							return legacyprotocol.CommandOverload{Parameters: lo.Map(o.Parameters, func(p protocol.CommandParameter, _ int) legacyprotocol.CommandParameter {
-								return legacyprotocol.CommandParameter{
-									Name:                p.Name,
-									Type:                p.Type,
-									Optional:            p.Optional,
-									CollapseEnumOptions: true,
-									//Enum:                legacyprotocol.CommandEnum(p.Enum),
-									//Suffix:              p.Suffix,
-								}
-							})}
-						}),
-					}
-				}),
+				Commands: commands,
 			},
 		}
+	case *packet.UpdateSoftEnum:
+		if !stateOf(conn).downgradeSoftEnumUpdate(pk) {
+			return nil
+		}
+	case *packet.ItemStackResponse:
+		return stateOf(conn).inventory.respond(m, pk.Responses)
+	case *packet.CraftingData:
+		stateOf(conn).inventory.addRecipes(pk.Recipes, pk.ClearRecipes)
 	case *packet.CreativeContent:
+		stateOf(conn).inventory.setCreativeItems(pk.Items)
 		return []packet.Packet{
 			&legacypacket.InventoryContent{
 				WindowID: 121,
 				Content: lo.Map(pk.Items, func(instance protocol.CreativeItem, _ int) legacyprotocol.ItemStack {
-					return downgradeItem(instance.Item)
+					return downgradeItem(m, instance.Item)
 				}),
 			},
 		}
 	case *packet.LevelSoundEvent:
-		if pk.SoundType == 113 || pk.SoundType == 145 || pk.SoundType == 151 || pk.SoundType <= 198 && pk.SoundType >= 195 || pk.SoundType == 222 || pk.SoundType == 227 {
+		downgraded, ok := stateOf(conn).downgradeSoundEvent(pk)
+		if !ok {
 			return nil
 		}
+		return []packet.Packet{downgraded}
 	case *packet.PlayerSkin:
-		var patch struct {
-			Geometry struct {
-				Default string
-			}
-		}
-		_ = json.Unmarshal(pk.Skin.SkinResourcePatch, &patch)
+		skin := downgradeSkin(pk.Skin)
 		return []packet.Packet{
 			&legacypacket.PlayerSkin{
 				UUID:             pk.UUID,
 				SkinID:           pk.Skin.SkinID,
 				NewSkinName:      pk.NewSkinName,
 				OldSkinName:      pk.OldSkinName,
-				SkinData:         pk.Skin.SkinData,
-				CapeData:         pk.Skin.CapeData,
-				SkinGeometryName: patch.Geometry.Default,
-				SkinGeometry:     pk.Skin.SkinGeometry,
+				SkinData:         skin.data,
+				CapeData:         skin.capeData,
+				SkinGeometryName: skin.geometryName,
+				SkinGeometry:     skin.geometry,
 				PremiumSkin:      pk.Skin.PremiumSkin,
 			},
 		}
@@ -739,24 +834,26 @@ func (Protocol) ConvertFromLatest(pk packet.Packet, conn *minecraft.Conn) []pack
 }
 
 var (
-	// latestAirRID is the runtime ID of the air block in the latest version of the game.
-	latestAirRID, _ = latestmappings.StateToRuntimeID("minecraft:air", nil)
 	// legacyAirRID is the runtime ID of the air block in the v1.12.0 version.
 	legacyAirRID = legacymappings.StateToRuntimeID("minecraft:air", nil)
 )
 
 // downgradeItem downgrades the input item stack to a legacy item stack. It returns a boolean indicating if the item was
 // downgraded successfully.
-func downgradeItem(input protocol.ItemStack) legacyprotocol.ItemStack {
-	name, _ := latestmappings.ItemRuntimeIDToName(input.NetworkID)
-	networkID, _ := legacymappings.ItemIDByName(name)
+func downgradeItem(m *latestmappings.Mappings, input protocol.ItemStack) legacyprotocol.ItemStack {
+	name, _ := m.ItemRuntimeIDToName(input.NetworkID)
+	networkID, meta, ok := legacymappings.ItemByName(name, int16(input.MetadataValue))
+	meta, data := legacyprotocol.DowngradeItemNBT(name, meta, input.NBTData)
+	if !ok && name != "" {
+		data = withItemName(data, name)
+	}
 	return legacyprotocol.ItemStack{
 		ItemType: legacyprotocol.ItemType{
 			NetworkID:     int32(networkID),
-			MetadataValue: int16(input.MetadataValue),
+			MetadataValue: meta,
 		},
 		Count:         int16(input.Count),
-		NBTData:       input.NBTData,
+		NBTData:       data,
 		CanBePlacedOn: input.CanBePlacedOn,
 		CanBreak:      input.CanBreak,
 	}
@@ -764,74 +861,96 @@ func downgradeItem(input protocol.ItemStack) legacyprotocol.ItemStack {
 
 // upgradeItem upgrades the input item stack to the latest item stack. It returns a boolean indicating if the item was
 // upgraded successfully.
-func upgradeItem(input legacyprotocol.ItemStack) protocol.ItemStack {
+func upgradeItem(m *latestmappings.Mappings, input legacyprotocol.ItemStack) protocol.ItemStack {
 	if input.ItemType.NetworkID == 0 {
 		return protocol.ItemStack{}
 	}
-	name, _ := legacymappings.ItemNameByID(int16(input.ItemType.NetworkID))
-	networkID, _ := latestmappings.ItemNameToRuntimeID(name)
+	name, meta, _ := legacymappings.ItemByID(int16(input.ItemType.NetworkID), input.ItemType.MetadataValue)
+	networkID, _ := m.ItemNameToRuntimeID(name)
+	meta, data := legacyprotocol.UpgradeItemNBT(name, meta, input.NBTData)
 	return protocol.ItemStack{
 		ItemType: protocol.ItemType{
 			NetworkID:     networkID,
-			MetadataValue: uint32(input.ItemType.MetadataValue),
+			MetadataValue: uint32(meta),
 		},
 		Count:         uint16(input.Count),
-		NBTData:       input.NBTData,
+		NBTData:       data,
 		CanBePlacedOn: input.CanBePlacedOn,
 		CanBreak:      input.CanBreak,
 	}
 }
 
-// downgradeBlockRuntimeID downgrades the latest block runtime ID to a v1.12.0 block runtime ID.
-func downgradeBlockRuntimeID(input uint32) uint32 {
-	name, properties, ok := latestmappings.RuntimeIDToState(input)
-	if !ok {
-		return legacyAirRID
+// withItemName returns the item NBT passed with a custom name holding the readable name of the item with the name
+// passed, so that players know which item they are holding if it was replaced by a similar item. Items that already
+// have a custom name keep it.
+func withItemName(data map[string]any, name string) map[string]any {
+	display, _ := data["display"].(map[string]any)
+	if _, ok := display["Name"]; ok {
+		return data
 	}
-	return legacymappings.StateToRuntimeID(name, properties)
+	data, display = maps.Clone(data), maps.Clone(display)
+	if data == nil {
+		data = make(map[string]any)
+	}
+	if display == nil {
+		display = make(map[string]any)
+	}
+	display["Name"] = "§r" + readableName(name)
+	data["display"] = display
+	return data
 }
 
-// upgradeBlockRuntimeID upgrades a v1.12.0 block runtime ID to the latest block runtime ID.
-func upgradeBlockRuntimeID(input uint32) uint32 {
-	name, properties, ok := legacymappings.RuntimeIDToState(input)
-	if !ok {
-		return latestAirRID
-	}
-	runtimeID, ok := latestmappings.StateToRuntimeID(name, properties)
-	if !ok {
-		return latestAirRID
+// readableName turns the identifier of an item or entity passed, such as minecraft:glow_ink_sac, into a readable
+// name, such as Glow Ink Sac. The namespace is dropped, which also gives custom items and entities a readable name.
+func readableName(name string) string {
+	words := strings.Split(name[strings.IndexByte(name, ':')+1:], "_")
+	for i, w := range words {
+		if w != "" {
+			words[i] = strings.ToUpper(w[:1]) + w[1:]
+		}
 	}
-	return runtimeID
+	return strings.Join(words, " ")
 }
 
-// downgradeChunk downgrades a chunk from the latest version to the v1.12.0 equivalent.
-func downgradeChunk(chunk *chunk.Chunk) *legacychunk.Chunk {
+// downgradeChunk downgrades a chunk from the latest version to the v1.12.0 equivalent with the range passed. The
+// vertical offset passed is the Y value in the latest version that ends up at the bottom of the legacy range. Sub
+// chunks outside the legacy range are discarded.
+func (s *state) downgradeChunk(chunk *chunk.Chunk, r cube.Range, verticalOffset int32) *legacychunk.Chunk {
 	// First downgrade the blocks.
-	downgraded := legacychunk.New(legacyAirRID)
-	for subInd, sub := range chunk.Sub()[4 : len(chunk.Sub())-4] {
+	downgraded := legacychunk.New(legacyAirRID, r)
+	airRID := s.mappings().AirRuntimeID()
+	offset := (r[0] + int(verticalOffset) - chunk.Range()[0]) >> 4
+	for subInd := range downgraded.Sub() {
+		if subInd+offset < 0 || subInd+offset >= len(chunk.Sub()) {
+			continue
+		}
+		sub := chunk.Sub()[subInd+offset]
 		for layerInd, layer := range sub.Layers() {
 			downgradedLayer := downgraded.Sub()[subInd].Layer(uint8(layerInd))
 			for x := uint8(0); x < 16; x++ {
 				for z := uint8(0); z < 16; z++ {
 					for y := uint8(0); y < 16; y++ {
 						latestRuntimeID := layer.At(x, y, z)
-						if latestRuntimeID == latestAirRID {
+						if latestRuntimeID == airRID {
 							// Don't bother with air.
 							continue
 						}
 
-						downgradedLayer.SetRuntimeID(x, y, z, downgradeBlockRuntimeID(latestRuntimeID))
+						downgradedLayer.SetRuntimeID(x, y, z, s.downgradeBlockRuntimeID(latestRuntimeID))
 					}
 				}
 			}
 		}
 	}
 
-	// Then downgrade the biomes.
+	// Then downgrade the biomes, of which we only have one per column.
+	visible := cube.Range{
+		max(r[0]+int(verticalOffset), chunk.Range()[0]),
+		min(r[1]+int(verticalOffset), chunk.Range()[1]),
+	}
 	for x := uint8(0); x < 16; x++ {
 		for z := uint8(0); z < 16; z++ {
-			// Use the highest block as an estimate for the biome, since we only have 2D biomes.
-			downgraded.SetBiomeID(x, z, uint8(chunk.Biome(x, chunk.HighestBlock(x, z), z)))
+			downgraded.SetBiomeID(x, z, legacymappings.BiomeID(columnBiome(chunk, x, z, visible)))
 		}
 	}
 	return downgraded
@@ -0,0 +1,364 @@
+package tedac
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"github.com/sandertv/gophertunnel/minecraft/protocol"
+	"github.com/sandertv/gophertunnel/minecraft/protocol/login"
+	"math"
+	"strings"
+)
+
+const (
+	// defaultGeometryName is the name of the built-in geometry used for skins without custom geometry.
+	defaultGeometryName = "geometry.humanoid.custom"
+	// legacyCapeWidth and legacyCapeHeight are the only dimensions of cape images that v1.12.0 accepts.
+	legacyCapeWidth, legacyCapeHeight = 64, 32
+)
+
+// builtinGeometries holds the names of geometry built into v1.12.0 clients that may be used without sending the
+// geometry data along with the skin.
+var builtinGeometries = map[string]struct{}{
+	defaultGeometryName:            {},
+	"geometry.humanoid.customSlim": {},
+}
+
+// defaultSkinData is the image of the skin shown to v1.12.0 clients for players with a skin that cannot be
+// converted. It roughly resembles Steve, using the 64x64 layout of the built-in humanoid geometry.
+var defaultSkinData = func() []byte {
+	data := make([]byte, 64*64*4)
+	for _, r := range []struct {
+		x, y, w, h int
+		c          [4]byte
+	}{
+		{0, 0, 32, 16, [4]byte{0xb4, 0x84, 0x6d, 0xff}},   // Head.
+		{8, 0, 16, 8, [4]byte{0x2b, 0x1e, 0x0d, 0xff}},    // Hair.
+		{0, 16, 16, 16, [4]byte{0x3a, 0x32, 0x89, 0xff}},  // Right leg.
+		{16, 16, 24, 16, [4]byte{0x00, 0xa8, 0xa8, 0xff}}, // Body.
+		{40, 16, 16, 16, [4]byte{0xb4, 0x84, 0x6d, 0xff}}, // Right arm.
+		{16, 48, 16, 16, [4]byte{0x3a, 0x32, 0x89, 0xff}}, // Left leg.
+		{32, 48, 16, 16, [4]byte{0xb4, 0x84, 0x6d, 0xff}}, // Left arm.
+	} {
+		for y := r.y; y < r.y+r.h; y++ {
+			for x := r.x; x < r.x+r.w; x++ {
+				copy(data[(y*64+x)*4:], r.c[:])
+			}
+		}
+	}
+	return data
+}()
+
+// legacySkin is a skin in the format used by v1.12.0 clients.
+type legacySkin struct {
+	data, capeData []byte
+	geometryName   string
+	geometry       []byte
+}
+
+// downgradeSkin converts a skin of the latest version to the format used by v1.12.0. The image is resampled to one
+// of the sizes v1.12.0 supports and the geometry is converted to the v1.8.0 geometry format. Persona skins are shown
+// using the image they were flattened into. If the skin cannot be converted, a default skin is returned instead.
+//
+// The persona pieces and piece tint colours of a skin are not flattened into an image here: they only reference
+// textures by piece and pack ID, and those textures are part of the client's persona packs, which are never sent
+// over the network. A persona skin without a flattened image is therefore shown as the default skin.
+func downgradeSkin(s protocol.Skin) legacySkin {
+	skin, ok := convertSkin(s)
+	if !ok {
+		skin = legacySkin{data: defaultSkinData, geometryName: defaultGeometryName}
+	}
+	if len(s.CapeData) > 0 {
+		skin.capeData = resample(s.CapeData, int(s.CapeImageWidth), int(s.CapeImageHeight), legacyCapeWidth, legacyCapeHeight)
+	}
+	return skin
+}
+
+// convertSkin attempts to convert the image and geometry of a skin to v1.12.0. False is returned if either could
+// not be converted.
+func convertSkin(s protocol.Skin) (legacySkin, bool) {
+	width, height, ok := legacySkinSize(int(s.SkinImageWidth), int(s.SkinImageHeight))
+	if !ok || len(s.SkinData) != int(s.SkinImageWidth*s.SkinImageHeight*4) || !visible(s.SkinData) {
+		// Persona skins are not always flattened into the image sent, in which case there is nothing to show, as
+		// the textures of the persona pieces are not available to us.
+		return legacySkin{}, false
+	}
+	var patch struct {
+		Geometry struct {
+			Default string
+		}
+	}
+	_ = json.Unmarshal(s.SkinResourcePatch, &patch)
+
+	skin := legacySkin{
+		data:         resample(s.SkinData, int(s.SkinImageWidth), int(s.SkinImageHeight), width, height),
+		geometryName: patch.Geometry.Default,
+	}
+	if _, ok := builtinGeometries[skin.geometryName]; ok {
+		return skin, true
+	}
+	geometry, names, err := downgradeGeometry(s.SkinGeometry)
+	if err != nil {
+		return legacySkin{}, false
+	}
+	if _, ok := names[skin.geometryName]; !ok {
+		return legacySkin{}, false
+	}
+	skin.geometry = geometry
+	return skin, true
+}
+
+// UpgradeSkin makes sure the skin of the client data of a v1.12.0 client passed is valid in the latest version. The
+// dimensions of the skin and cape are derived from the size of their images, and the skin is replaced by a default
+// skin if its size is not one that v1.12.0 supports.
+func UpgradeSkin(data *login.ClientData) {
+	skin, _ := base64.StdEncoding.DecodeString(data.SkinData)
+	switch len(skin) {
+	case 64 * 32 * 4:
+		data.SkinImageWidth, data.SkinImageHeight = 64, 32
+	case 64 * 64 * 4:
+		data.SkinImageWidth, data.SkinImageHeight = 64, 64
+	case 128 * 128 * 4:
+		data.SkinImageWidth, data.SkinImageHeight = 128, 128
+	default:
+		data.SkinData = base64.StdEncoding.EncodeToString(defaultSkinData)
+		data.SkinImageWidth, data.SkinImageHeight = 64, 64
+	}
+	data.CapeImageWidth, data.CapeImageHeight = 0, 0
+	if cape, _ := base64.StdEncoding.DecodeString(data.CapeData); len(cape) == legacyCapeWidth*legacyCapeHeight*4 {
+		data.CapeImageWidth, data.CapeImageHeight = legacyCapeWidth, legacyCapeHeight
+	} else {
+		data.CapeData = ""
+	}
+}
+
+// legacySkinSize returns the size that a skin image with the dimensions passed should be resampled to for v1.12.0,
+// which only supports 64x32, 64x64 and 128x128 skins. False is returned if the image cannot be resampled to any of
+// these sizes without distorting it.
+func legacySkinSize(width, height int) (int, int, bool) {
+	switch {
+	case width <= 0 || height <= 0:
+		return 0, 0, false
+	case width == height*2:
+		return 64, 32, true
+	case width == height && width >= 128:
+		return 128, 128, true
+	case width == height:
+		return 64, 64, true
+	}
+	return 0, 0, false
+}
+
+// resample resamples the RGBA image passed with the dimensions passed to an image with the new dimensions passed,
+// using nearest neighbour sampling so that the pixels of the skin remain sharp. An image of the new size that is
+// fully transparent is returned if the image passed does not match its dimensions.
+func resample(data []byte, width, height, newWidth, newHeight int) []byte {
+	if width == newWidth && height == newHeight && len(data) == width*height*4 {
+		return data
+	}
+	resampled := make([]byte, newWidth*newHeight*4)
+	if width <= 0 || height <= 0 || len(data) != width*height*4 {
+		return resampled
+	}
+	for y := 0; y < newHeight; y++ {
+		srcY := y * height / newHeight
+		for x := 0; x < newWidth; x++ {
+			srcX := x * width / newWidth
+			copy(resampled[(y*newWidth+x)*4:(y*newWidth+x)*4+4], data[(srcY*width+srcX)*4:])
+		}
+	}
+	return resampled
+}
+
+// visible checks if the RGBA image passed has at least one pixel that is not fully transparent.
+func visible(data []byte) bool {
+	for i := 3; i < len(data); i += 4 {
+		if data[i] != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+type (
+	// geometryFile is a geometry file in format version 1.12.0 or later.
+	geometryFile struct {
+		Geometry []geometry `json:"minecraft:geometry"`
+	}
+	// geometry is a single geometry in format version 1.12.0 or later.
+	geometry struct {
+		Description struct {
+			Identifier          string    `json:"identifier"`
+			TextureWidth        int       `json:"texture_width"`
+			TextureHeight       int       `json:"texture_height"`
+			VisibleBoundsWidth  float64   `json:"visible_bounds_width"`
+			VisibleBoundsHeight float64   `json:"visible_bounds_height"`
+			VisibleBoundsOffset []float64 `json:"visible_bounds_offset"`
+		} `json:"description"`
+		Bones []geometryBone `json:"bones"`
+	}
+	// geometryBone is a bone of geometry in format version 1.12.0 or later.
+	geometryBone struct {
+		Name     string         `json:"name"`
+		Parent   string         `json:"parent"`
+		Pivot    []float64      `json:"pivot"`
+		Rotation []float64      `json:"rotation"`
+		Mirror   bool           `json:"mirror"`
+		Inflate  float64        `json:"inflate"`
+		Cubes    []geometryCube `json:"cubes"`
+	}
+	// geometryCube is a cube of a bone in format version 1.12.0 or later. Its UV is either the offset of a box UV,
+	// or an object holding the UV of every face separately.
+	geometryCube struct {
+		Origin   []float64       `json:"origin"`
+		Size     []float64       `json:"size"`
+		UV       json.RawMessage `json:"uv"`
+		Inflate  float64         `json:"inflate"`
+		Mirror   *bool           `json:"mirror"`
+		Pivot    []float64       `json:"pivot"`
+		Rotation []float64       `json:"rotation"`
+	}
+
+	// legacyGeometry is a single geometry in format version 1.8.0, which is used by v1.12.0 clients.
+	legacyGeometry struct {
+		TextureWidth        int          `json:"texturewidth"`
+		TextureHeight       int          `json:"textureheight"`
+		VisibleBoundsWidth  float64      `json:"visible_bounds_width,omitempty"`
+		VisibleBoundsHeight float64      `json:"visible_bounds_height,omitempty"`
+		VisibleBoundsOffset []float64    `json:"visible_bounds_offset,omitempty"`
+		Bones               []legacyBone `json:"bones"`
+	}
+	// legacyBone is a bone of geometry in format version 1.8.0.
+	legacyBone struct {
+		Name     string       `json:"name"`
+		Parent   string       `json:"parent,omitempty"`
+		Pivot    []float64    `json:"pivot,omitempty"`
+		Rotation []float64    `json:"rotation,omitempty"`
+		Mirror   bool         `json:"mirror,omitempty"`
+		Cubes    []legacyCube `json:"cubes,omitempty"`
+	}
+	// legacyCube is a cube of a bone in format version 1.8.0, which only supports box UVs.
+	legacyCube struct {
+		Origin  []float64  `json:"origin"`
+		Size    []float64  `json:"size"`
+		UV      [2]float64 `json:"uv"`
+		Inflate float64    `json:"inflate,omitempty"`
+		Mirror  *bool      `json:"mirror,omitempty"`
+	}
+)
+
+// downgradeGeometry converts the skin geometry data passed to format version 1.8.0. Geometry data that is already
+// in this format is returned as is. The names of all geometries in the data are returned, so that the caller can
+// check if the geometry of a skin is present.
+func downgradeGeometry(data []byte) ([]byte, map[string]struct{}, error) {
+	var top map[string]json.RawMessage
+	if err := json.Unmarshal(data, &top); err != nil {
+		return nil, nil, fmt.Errorf("decode geometry: %w", err)
+	}
+	names := make(map[string]struct{})
+	if _, ok := top["minecraft:geometry"]; !ok {
+		for name := range top {
+			if strings.HasPrefix(name, "geometry.") {
+				// Geometry in the old format may inherit from other geometry using 'name:parent'.
+				name, _, _ = strings.Cut(name, ":")
+				names[name] = struct{}{}
+			}
+		}
+		return data, names, nil
+	}
+	var f geometryFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, nil, fmt.Errorf("decode geometry: %w", err)
+	}
+	legacy := map[string]any{"format_version": "1.8.0"}
+	for _, g := range f.Geometry {
+		if g.Description.Identifier == "" {
+			continue
+		}
+		legacy[g.Description.Identifier] = downgradeGeometryBones(g)
+		names[g.Description.Identifier] = struct{}{}
+	}
+	b, err := json.Marshal(legacy)
+	if err != nil {
+		return nil, nil, fmt.Errorf("encode geometry: %w", err)
+	}
+	return b, names, nil
+}
+
+// downgradeGeometryBones converts a single geometry to format version 1.8.0. Cubes with a rotation of their own are
+// moved into a new bone with that rotation, as cubes cannot be rotated in format version 1.8.0. Poly meshes and
+// other features that did not yet exist are dropped.
+func downgradeGeometryBones(g geometry) legacyGeometry {
+	legacy := legacyGeometry{
+		TextureWidth:        g.Description.TextureWidth,
+		TextureHeight:       g.Description.TextureHeight,
+		VisibleBoundsWidth:  g.Description.VisibleBoundsWidth,
+		VisibleBoundsHeight: g.Description.VisibleBoundsHeight,
+		VisibleBoundsOffset: g.Description.VisibleBoundsOffset,
+		Bones:               make([]legacyBone, 0, len(g.Bones)),
+	}
+	for _, b := range g.Bones {
+		bone := legacyBone{Name: b.Name, Parent: b.Parent, Pivot: b.Pivot, Rotation: b.Rotation, Mirror: b.Mirror}
+		var rotated []legacyBone
+		for i, c := range b.Cubes {
+			uv, ok := boxUV(c)
+			if !ok {
+				continue
+			}
+			cube := legacyCube{Origin: c.Origin, Size: c.Size, UV: uv, Inflate: c.Inflate + b.Inflate, Mirror: c.Mirror}
+			if !rotatedCube(c) {
+				bone.Cubes = append(bone.Cubes, cube)
+				continue
+			}
+			pivot := c.Pivot
+			if len(pivot) != 3 {
+				pivot = b.Pivot
+			}
+			rotated = append(rotated, legacyBone{
+				Name:     fmt.Sprintf("%v_cube%v", b.Name, i),
+				Parent:   b.Name,
+				Pivot:    pivot,
+				Rotation: c.Rotation,
+				Mirror:   b.Mirror,
+				Cubes:    []legacyCube{cube},
+			})
+		}
+		legacy.Bones = append(append(legacy.Bones, bone), rotated...)
+	}
+	return legacy
+}
+
+// rotatedCube checks if the cube passed has a rotation of its own.
+func rotatedCube(c geometryCube) bool {
+	for _, r := range c.Rotation {
+		if r != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// boxUV returns the box UV offset of the cube passed. If the cube has a separate UV for every face, the box UV is
+// derived from its north face, which is where the box UV layout places it. False is returned if the cube has no
+// usable UV.
+func boxUV(c geometryCube) ([2]float64, bool) {
+	var uv [2]float64
+	if len(c.UV) == 0 {
+		return uv, true
+	}
+	if err := json.Unmarshal(c.UV, &uv); err == nil {
+		return uv, true
+	}
+	var faces map[string]struct {
+		UV []float64 `json:"uv"`
+	}
+	if err := json.Unmarshal(c.UV, &faces); err != nil {
+		return uv, false
+	}
+	north, ok := faces["north"]
+	if !ok || len(north.UV) != 2 || len(c.Size) != 3 {
+		return uv, false
+	}
+	depth := math.Floor(c.Size[2])
+	return [2]float64{north.UV[0] - depth, north.UV[1] - depth}, true
+}
@@ -0,0 +1,72 @@
+package tedac
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestLegacySkinSize(t *testing.T) {
+	tests := []struct {
+		width, height         int
+		wantWidth, wantHeight int
+		wantOK                bool
+	}{
+		{width: 64, height: 32, wantWidth: 64, wantHeight: 32, wantOK: true},
+		{width: 128, height: 64, wantWidth: 64, wantHeight: 32, wantOK: true},
+		{width: 64, height: 64, wantWidth: 64, wantHeight: 64, wantOK: true},
+		{width: 32, height: 32, wantWidth: 64, wantHeight: 64, wantOK: true},
+		{width: 128, height: 128, wantWidth: 128, wantHeight: 128, wantOK: true},
+		{width: 256, height: 256, wantWidth: 128, wantHeight: 128, wantOK: true},
+		{width: 64, height: 48},
+		{width: 0, height: 0},
+		{width: -64, height: -32},
+	}
+	for _, test := range tests {
+		width, height, ok := legacySkinSize(test.width, test.height)
+		if width != test.wantWidth || height != test.wantHeight || ok != test.wantOK {
+			t.Errorf("legacySkinSize(%v, %v) = (%v, %v, %v), want (%v, %v, %v)", test.width, test.height, width, height, ok, test.wantWidth, test.wantHeight, test.wantOK)
+		}
+	}
+}
+
+func TestResample(t *testing.T) {
+	// A 2x2 image with four differently coloured pixels.
+	image := []byte{
+		1, 1, 1, 255, 2, 2, 2, 255,
+		3, 3, 3, 255, 4, 4, 4, 255,
+	}
+	tests := []struct {
+		name                string
+		data                []byte
+		width, height       int
+		newWidth, newHeight int
+		want                []byte
+	}{
+		{
+			name: "same size", data: image, width: 2, height: 2, newWidth: 2, newHeight: 2,
+			want: image,
+		},
+		{
+			name: "upscale", data: image, width: 2, height: 2, newWidth: 4, newHeight: 2,
+			want: []byte{
+				1, 1, 1, 255, 1, 1, 1, 255, 2, 2, 2, 255, 2, 2, 2, 255,
+				3, 3, 3, 255, 3, 3, 3, 255, 4, 4, 4, 255, 4, 4, 4, 255,
+			},
+		},
+		{
+			name: "downscale", data: image, width: 2, height: 2, newWidth: 1, newHeight: 1,
+			want: []byte{1, 1, 1, 255},
+		},
+		{
+			name: "invalid size", data: image[:8], width: 2, height: 2, newWidth: 1, newHeight: 2,
+			want: make([]byte, 8),
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := resample(test.data, test.width, test.height, test.newWidth, test.newHeight); !bytes.Equal(got, test.want) {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
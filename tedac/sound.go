@@ -0,0 +1,147 @@
+package tedac
+
+import (
+	"github.com/didntpot/tedac/tedac/legacymappings"
+	"github.com/sandertv/gophertunnel/minecraft/protocol/packet"
+)
+
+// soundEventDropped is used in legacySoundEvents for sounds that have no similar sound in v1.12.0.
+const soundEventDropped = ^uint32(0)
+
+// maxLegacySoundEvent is the highest sound event that exists in v1.12.0. Sound events up to this one are the same
+// in both versions, apart from those in legacySoundEvents.
+const maxLegacySoundEvent = packet.SoundEventFurnaceUse
+
+// legacySoundEvents maps sound events of the latest version that do not exist in v1.12.0 to a similar sound event
+// that does, or to soundEventDropped if there is none. Sound events above maxLegacySoundEvent that are not present
+// are dropped as well.
+var legacySoundEvents = map[uint32]uint32{
+	packet.SoundEventRecordNull:            soundEventDropped,
+	packet.SoundEventImitateBlaze:          soundEventDropped,
+	packet.SoundEventImitateEndermite:      soundEventDropped,
+	packet.SoundEventLtReactionGlowStick:   soundEventDropped,
+	packet.SoundEventLtReactionGlowStick2:  soundEventDropped,
+	packet.SoundEventLtReactionLuminol:     soundEventDropped,
+	packet.SoundEventLtReactionSalt:        soundEventDropped,
+	packet.SoundEventSpawnBaby:             soundEventDropped,
+	packet.SoundEventLayEgg:                soundEventDropped,
+	packet.SoundEventMilkSuspiciously:      packet.SoundEventMilk,
+	packet.SoundEventHoneybottleDrink:      packet.SoundEventDrink,
+	packet.SoundEventConvertToZombified:    packet.SoundEventConvertToDrowned,
+	packet.SoundEventStepLava:              packet.SoundEventStep,
+	packet.SoundEventAngry:                 packet.SoundEventMad,
+	packet.SoundEventRecordPigstep:         packet.SoundEventRecordCat,
+	packet.SoundEventUseSmithingTable:      packet.SoundEventSmithingTableUse,
+	packet.SoundEventEquipNetherite:        packet.SoundEventEquipDiamond,
+	packet.SoundEventSculkSensorPowerOn:    packet.SoundEventPowerOn,
+	packet.SoundEventSculkSensorPowerOff:   packet.SoundEventPowerOff,
+	packet.SoundEventBucketFillPowderSnow:  packet.SoundEventBucketFillWater,
+	packet.SoundEventBucketEmptyPowderSnow: packet.SoundEventBucketEmptyWater,
+	packet.SoundEventCaveVinesPickBerries:  packet.SoundEventSweetBerryBushPick,
+	packet.SoundEventPlayerHurtDrown:       packet.SoundEventHurt,
+	packet.SoundEventPlayerHurtOnFire:      packet.SoundEventHurt,
+	packet.SoundEventPlayerHurtFreeze:      packet.SoundEventHurt,
+	packet.SoundEventAmbientScreamer:       packet.SoundEventAmbient,
+	packet.SoundEventHurtScreamer:          packet.SoundEventHurt,
+	packet.SoundEventDeathScreamer:         packet.SoundEventDeath,
+	packet.SoundEventMilkScreamer:          packet.SoundEventMilk,
+	packet.SoundEventJumpToBlock:           packet.SoundEventJump,
+	packet.SoundEventRamImpact:             packet.SoundEventAttackStrong,
+	packet.SoundEventRamImpactScreamer:     packet.SoundEventAttackStrong,
+	packet.SoundEventConvertToStray:        packet.SoundEventConvertToDrowned,
+	packet.SoundEventCakeAddCandle:         packet.SoundEventPlace,
+	packet.SoundEventExtinguishCandle:      packet.SoundEventExtinguishFire,
+	packet.SoundEventRecordOtherside:       packet.SoundEventRecordCat,
+	packet.SoundEventSculkSensorPlace:      packet.SoundEventPlace,
+	packet.SoundEventSculkShriekerPlace:    packet.SoundEventPlace,
+	packet.SoundEventDrinkMilk:             packet.SoundEventDrink,
+	packet.SoundEventFrogspawnHatched:      packet.SoundEventTurtleEggHatched,
+	packet.SoundEventFrogspawnBreak:        packet.SoundEventTurtleEggBreak,
+	packet.SoundeventItemThrown:            packet.SoundEventThrow,
+	packet.SoundEventRecord5:               packet.SoundEventRecordCat,
+	packet.SoundEventStepSand:              packet.SoundEventStep,
+	packet.SoundEventPressurePlateClickOff: packet.SoundEventPowerOff,
+	packet.SoundEventPressurePlateClickOn:  packet.SoundEventPowerOn,
+	packet.SoundEventButtonClickOff:        packet.SoundEventPowerOff,
+	packet.SoundEventButtonClickOn:         packet.SoundEventPowerOn,
+	packet.SoundEventSnifferEggCrack:       packet.SoundEventTurtleEggCrack,
+	packet.SoundEventSnifferEggHatched:     packet.SoundEventTurtleEggHatched,
+	packet.SoundEventRecordRelic:           packet.SoundEventRecordCat,
+	packet.SoundEventConvertHuskToZombie:   packet.SoundEventConvertToDrowned,
+	packet.SoundEventPigDeath:              packet.SoundEventDeath,
+	packet.SoundEventCopperBulbTurnOn:      packet.SoundEventPowerOn,
+	packet.SoundEventCopperBulbTurnOff:     packet.SoundEventPowerOff,
+	packet.SoundEventImitateBogged:         packet.SoundEventImitateStray,
+	packet.SoundEventEquipWolf:             packet.SoundEventEquipGeneric,
+	packet.SoundEventHurtReduced:           packet.SoundEventHurt,
+	packet.SoundEventMaceSmashAir:          packet.SoundEventAttackStrong,
+	packet.SoundEventMaceSmashGround:       packet.SoundEventAttackStrong,
+	packet.SoundEventMaceHeavySmashGround:  packet.SoundEventAttackStrong,
+	packet.SoundEventRecordCreator:         packet.SoundEventRecordCat,
+	packet.SoundEventRecordCreatorMusicBox: packet.SoundEventRecordCat,
+	packet.SoundEventRecordPrecipice:       packet.SoundEventRecordCat,
+	packet.SoundEventImitateDrowned:        packet.SoundEventImitateZombie,
+}
+
+// doorSoundEvents holds the sound events of the latest version played when a door-like block is opened or closed.
+// v1.12.0 clients play these sounds using a LevelEvent instead.
+var doorSoundEvents = map[uint32]struct{}{
+	packet.SoundEventDoorOpen:       {},
+	packet.SoundEventDoorClose:      {},
+	packet.SoundEventTrapdoorOpen:   {},
+	packet.SoundEventTrapdoorClose:  {},
+	packet.SoundEventFenceGateOpen:  {},
+	packet.SoundEventFenceGateClose: {},
+}
+
+// blockSoundEvents holds the sound events of which the extra data is the runtime ID of the block that the sound is
+// played for.
+var blockSoundEvents = map[uint32]struct{}{
+	packet.SoundEventItemUseOn:  {},
+	packet.SoundEventHit:        {},
+	packet.SoundEventStep:       {},
+	packet.SoundEventBreak:      {},
+	packet.SoundEventPlace:      {},
+	packet.SoundEventHeavyStep:  {},
+	packet.SoundEventFall:       {},
+	packet.SoundEventLand:       {},
+	packet.SoundEventBreakBlock: {},
+}
+
+// downgradeSoundEvent downgrades a LevelSoundEvent packet to v1.12.0. Sounds that do not exist in v1.12.0 are
+// replaced by a similar sound, and the block runtime ID and entity type of the sound are downgraded. False is
+// returned if the sound cannot be played by v1.12.0 clients.
+func (s *state) downgradeSoundEvent(pk *packet.LevelSoundEvent) (packet.Packet, bool) {
+	if _, ok := doorSoundEvents[pk.SoundType]; ok {
+		return &packet.LevelEvent{EventType: packet.LevelEventSoundOpenDoor, Position: pk.Position}, true
+	}
+	soundType, ok := legacySoundEvents[pk.SoundType]
+	if !ok {
+		soundType = pk.SoundType
+		if soundType > maxLegacySoundEvent {
+			soundType = soundEventDropped
+		}
+	}
+	if soundType == soundEventDropped {
+		return nil, false
+	}
+	entityType := pk.EntityType
+	if entityType != "" {
+		if entityType, _, ok = legacymappings.EntityType(entityType); !ok {
+			// The sound belongs to an entity the client cannot see, so it would not know which sound to play.
+			return nil, false
+		}
+	}
+	extraData := pk.ExtraData
+	if _, ok := blockSoundEvents[soundType]; ok && extraData >= 0 {
+		extraData = int32(s.downgradeBlockRuntimeID(uint32(extraData)))
+	}
+	return &packet.LevelSoundEvent{
+		SoundType:             soundType,
+		Position:              pk.Position,
+		ExtraData:             extraData,
+		EntityType:            entityType,
+		BabyMob:               pk.BabyMob,
+		DisableRelativeVolume: pk.DisableRelativeVolume,
+	}, true
+}
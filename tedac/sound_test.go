@@ -0,0 +1,79 @@
+package tedac
+
+import (
+	"github.com/didntpot/tedac/tedac/latestmappings"
+	"github.com/sandertv/gophertunnel/minecraft/protocol/packet"
+	"testing"
+)
+
+func TestDowngradeSoundEvent(t *testing.T) {
+	airRID, _ := latestmappings.StateToRuntimeID("minecraft:air", nil)
+	tests := []struct {
+		name string
+		pk   *packet.LevelSoundEvent
+		want packet.Packet
+	}{
+		{
+			name: "unchanged",
+			pk:   &packet.LevelSoundEvent{SoundType: packet.SoundEventThrow, ExtraData: -1},
+			want: &packet.LevelSoundEvent{SoundType: packet.SoundEventThrow, ExtraData: -1},
+		},
+		{
+			name: "replaced",
+			pk:   &packet.LevelSoundEvent{SoundType: packet.SoundEventMilkSuspiciously, ExtraData: -1},
+			want: &packet.LevelSoundEvent{SoundType: packet.SoundEventMilk, ExtraData: -1},
+		},
+		{
+			name: "dropped",
+			pk:   &packet.LevelSoundEvent{SoundType: packet.SoundEventRecordNull},
+		},
+		{
+			name: "unknown",
+			pk:   &packet.LevelSoundEvent{SoundType: soundEventDropped - 1},
+		},
+		{
+			name: "door",
+			pk:   &packet.LevelSoundEvent{SoundType: packet.SoundEventDoorOpen},
+			want: &packet.LevelEvent{EventType: packet.LevelEventSoundOpenDoor},
+		},
+		{
+			name: "substituted entity",
+			pk:   &packet.LevelSoundEvent{SoundType: packet.SoundEventAmbient, EntityType: "minecraft:warden", ExtraData: -1},
+			want: &packet.LevelSoundEvent{SoundType: packet.SoundEventAmbient, EntityType: "minecraft:iron_golem", ExtraData: -1},
+		},
+		{
+			name: "unknown entity",
+			pk:   &packet.LevelSoundEvent{SoundType: packet.SoundEventAmbient, EntityType: "tedac:custom"},
+		},
+		{
+			name: "block",
+			pk:   &packet.LevelSoundEvent{SoundType: packet.SoundEventPlace, ExtraData: int32(airRID)},
+			want: &packet.LevelSoundEvent{SoundType: packet.SoundEventPlace, ExtraData: int32(legacyAirRID)},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := &state{}
+			got, ok := s.downgradeSoundEvent(test.pk)
+			if test.want == nil {
+				if ok {
+					t.Fatalf("got %#v, want sound to be dropped", got)
+				}
+				return
+			}
+			if !ok {
+				t.Fatalf("sound was dropped, want %#v", test.want)
+			}
+			switch want := test.want.(type) {
+			case *packet.LevelSoundEvent:
+				if got, ok := got.(*packet.LevelSoundEvent); !ok || *got != *want {
+					t.Errorf("got %#v, want %#v", got, want)
+				}
+			case *packet.LevelEvent:
+				if got, ok := got.(*packet.LevelEvent); !ok || *got != *want {
+					t.Errorf("got %#v, want %#v", got, want)
+				}
+			}
+		})
+	}
+}
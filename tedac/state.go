@@ -0,0 +1,63 @@
+package tedac
+
+import (
+	"github.com/df-mc/atomic"
+	"github.com/df-mc/dragonfly/server/block/cube"
+	"github.com/df-mc/dragonfly/server/world"
+	"github.com/didntpot/tedac/tedac/latestmappings"
+	"github.com/sandertv/gophertunnel/minecraft"
+	"sync"
+)
+
+// state holds the translation state of a single connection using the Protocol. Most packets can be translated
+// without knowing anything about the connection, but some, such as chunks, depend on earlier packets sent.
+type state struct {
+	// dimension is the dimension the client is currently in.
+	dimension atomic.Int32
+	// verticalOffset is the Y value in the latest version of the Overworld shown at Y=0 to the client. It
+	// is set using SetVerticalOffset.
+	verticalOffset atomic.Int32
+	// latestMappings holds the block states and items of the server, which may include custom blocks. It is set
+	// using SetMappings.
+	latestMappings atomic.Value[*latestmappings.Mappings]
+	// customBlockFallbacks holds the vanilla blocks shown instead of the custom blocks of the server. It is set
+	// using SetCustomBlockFallbacks.
+	customBlockFallbacks atomic.Value[customBlockFallbacks]
+	// softEnums holds the types of the soft enums sent to the client in the last AvailableCommands packet.
+	softEnums atomic.Value[map[string]struct{}]
+	// entities holds the entities of which the type was replaced by a similar entity type.
+	entities *entities
+	// inventory holds the contents of the windows of the client, used to translate inventory transactions.
+	inventory *inventory
+}
+
+// states holds the state of every connection currently using the Protocol, keyed by the *minecraft.Conn.
+var states sync.Map
+
+// stateOf returns the translation state of the connection passed, creating it if it did not yet exist.
+func stateOf(conn *minecraft.Conn) *state {
+	if s, ok := states.Load(conn); ok {
+		return s.(*state)
+	}
+	s := &state{entities: newEntities(), inventory: newInventory()}
+	s.dimension.Store(conn.GameData().Dimension)
+
+	actual, _ := states.LoadOrStore(conn, s)
+	return actual.(*state)
+}
+
+// Release releases all translation state held for the connection passed. It should be called once the connection
+// is closed.
+func Release(conn *minecraft.Conn) {
+	states.Delete(conn)
+}
+
+// DimensionRange returns the vertical range of the dimension with the ID passed in the latest version. Unknown
+// dimensions are treated as the Overworld.
+func DimensionRange(dimension int32) cube.Range {
+	dim, ok := world.DimensionByID(int(dimension))
+	if !ok {
+		return world.Overworld.Range()
+	}
+	return dim.Range()
+}
@@ -0,0 +1,297 @@
+package main
+
+import (
+	"errors"
+	"github.com/didntpot/tedac/tedac"
+	"github.com/didntpot/tedac/tedac/latestmappings"
+	"github.com/google/uuid"
+	"github.com/sandertv/gophertunnel/minecraft"
+	"github.com/sandertv/gophertunnel/minecraft/protocol"
+	"github.com/sandertv/gophertunnel/minecraft/protocol/packet"
+	"slices"
+	"sync"
+	"time"
+)
+
+// transferTimeout is the duration a player has to reconnect to Tedac after being transferred. If the player does not
+// reconnect within this time, it will be connected to the default remote server again.
+const transferTimeout = time.Second * 30
+
+// transfer is a pending transfer of a single player to a different remote server.
+type transfer struct {
+	address string
+	expiry  time.Time
+}
+
+// transfers keeps track of the pending transfers of players, keyed by their identity UUID. The XUID cannot be used,
+// as it is empty for every player that is not authenticated with Xbox Live.
+type transfers struct {
+	mu sync.Mutex
+	m  map[string]transfer
+}
+
+// newTransfers ...
+func newTransfers() *transfers {
+	return &transfers{m: make(map[string]transfer)}
+}
+
+// add registers a pending transfer of the player with the identity passed to the address passed. Transfers of
+// players without an identity are not registered, as they could not be told apart from other players.
+func (tr *transfers) add(identity, address string) {
+	if identity == "" {
+		return
+	}
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+
+	now := time.Now()
+	for id, pending := range tr.m {
+		if now.After(pending.expiry) {
+			delete(tr.m, id)
+		}
+	}
+	tr.m[identity] = transfer{address: address, expiry: now.Add(transferTimeout)}
+}
+
+// take returns and removes the pending transfer of the player with the identity passed. If the player has no
+// pending transfer, or if it has expired, false is returned.
+func (tr *transfers) take(identity string) (string, bool) {
+	if identity == "" {
+		return "", false
+	}
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+
+	pending, ok := tr.m[identity]
+	if !ok {
+		return "", false
+	}
+	delete(tr.m, identity)
+	if time.Now().After(pending.expiry) {
+		return "", false
+	}
+	return pending.address, true
+}
+
+// shownState holds everything a remote server showed to the client that does not disappear by itself when the
+// client is transferred to another server.
+type shownState struct {
+	entities   map[int64]struct{}
+	players    map[uuid.UUID]struct{}
+	objectives map[string]struct{}
+	bossBars   map[int64]struct{}
+	effects    map[int32]struct{}
+}
+
+// newShownState ...
+func newShownState() shownState {
+	return shownState{
+		entities:   make(map[int64]struct{}),
+		players:    make(map[uuid.UUID]struct{}),
+		objectives: make(map[string]struct{}),
+		bossBars:   make(map[int64]struct{}),
+		effects:    make(map[int32]struct{}),
+	}
+}
+
+// Transfer transfers the Session to the remote server at the address passed without disconnecting the client. The
+// client is reset as if it changed dimension, and all entities, player list entries, scoreboards, boss bars, effects
+// and items of the previous server are removed. The runtime ID of the player on the new server is remapped to the
+// one the client was spawned with.
+//
+// The block palette and item registry of a client are only sent when the game starts. v1.12.0 clients are shown
+// the blocks and items of the new server through their translation tables, but other clients cannot be updated, so
+// transferring them to a server with different custom blocks or items fails.
+func (s *Session) Transfer(address string) error {
+	serverConn, err := minecraft.Dialer{
+		TokenSource: s.t.src,
+		ClientData:  s.clientData,
+	}.Dial("raknet", address)
+	if err != nil {
+		return err
+	}
+	if err := serverConn.DoSpawn(); err != nil {
+		_ = serverConn.Close()
+		return err
+	}
+	data := serverConn.GameData()
+	if !s.Legacy() && !samePalette(s.GameData(), data) {
+		_ = serverConn.Close()
+		return errors.New("remote server has different custom blocks or items")
+	}
+
+	s.mu.Lock()
+	oldConn := s.serverConn
+	s.serverConn, s.data = serverConn, data
+	s.rid, s.uid = data.EntityRuntimeID, data.EntityUniqueID
+	shown := s.shown
+	s.shown = newShownState()
+	clear(s.biomeBufferCache)
+	s.mu.Unlock()
+
+	// Closing the old connection stops the goroutine reading from it, as the Session no longer uses it.
+	_ = oldConn.Close()
+
+	s.dimension.Store(data.Dimension)
+	s.pos.Store(data.PlayerPosition)
+	s.lastPos.Store(data.PlayerPosition)
+	s.yaw.Store(data.Yaw)
+	s.pitch.Store(data.Pitch)
+	s.tick.Store(uint64(data.Time))
+	s.oldMovementSystem.Store(s.usesOldMovementSystem(data))
+	if s.Legacy() {
+		// The game is not started again, so the mappings of the new server must be set manually.
+		tedac.SetMappings(s.conn, latestmappings.New(data.CustomBlocks, data.UseBlockNetworkIDHashes, data.Items))
+	}
+
+	s.resetClient(data, shown)
+	go s.handleServerPackets(serverConn)
+	return nil
+}
+
+// resetClient clears everything sent to the client by the previous remote server and sends the game data of the new
+// remote server.
+func (s *Session) resetClient(data minecraft.GameData, shown shownState) {
+	for uniqueID := range shown.entities {
+		_ = s.conn.WritePacket(&packet.RemoveActor{EntityUniqueID: uniqueID})
+	}
+	if len(shown.players) > 0 {
+		entries := make([]protocol.PlayerListEntry, 0, len(shown.players))
+		for id := range shown.players {
+			entries = append(entries, protocol.PlayerListEntry{UUID: id})
+		}
+		_ = s.conn.WritePacket(&packet.PlayerList{ActionType: packet.PlayerListActionRemove, Entries: entries})
+	}
+	for name := range shown.objectives {
+		_ = s.conn.WritePacket(&packet.RemoveObjective{ObjectiveName: name})
+	}
+	for uniqueID := range shown.bossBars {
+		_ = s.conn.WritePacket(&packet.BossEvent{BossEntityUniqueID: uniqueID, EventType: packet.BossEventHide})
+	}
+	for effectType := range shown.effects {
+		_ = s.conn.WritePacket(&packet.MobEffect{EntityRuntimeID: s.clientRID, Operation: packet.MobEffectRemove, EffectType: effectType})
+	}
+
+	_ = s.conn.WritePacket(&packet.InventoryContent{WindowID: protocol.WindowIDInventory, Content: make([]protocol.ItemInstance, 36)})
+	_ = s.conn.WritePacket(&packet.InventoryContent{WindowID: protocol.WindowIDArmour, Content: make([]protocol.ItemInstance, 4)})
+	_ = s.conn.WritePacket(&packet.InventoryContent{WindowID: protocol.WindowIDOffHand, Content: make([]protocol.ItemInstance, 1)})
+
+	// Changing to a different dimension and back makes the client unload all chunks it currently has loaded.
+	temporaryDimension := int32(packet.DimensionNether)
+	if data.Dimension == temporaryDimension {
+		temporaryDimension = packet.DimensionOverworld
+	}
+	s.pendingDimensionChanges.Add(2)
+	_ = s.conn.WritePacket(&packet.ChangeDimension{Dimension: temporaryDimension, Position: data.PlayerPosition})
+	_ = s.conn.WritePacket(&packet.ChangeDimension{Dimension: data.Dimension, Position: data.PlayerPosition})
+
+	_ = s.conn.WritePacket(&packet.SetPlayerGameType{GameType: data.PlayerGameMode})
+	_ = s.conn.WritePacket(&packet.SetDifficulty{Difficulty: uint32(data.Difficulty)})
+	_ = s.conn.WritePacket(&packet.SetTime{Time: int32(data.Time)})
+	_ = s.conn.WritePacket(&packet.GameRulesChanged{GameRules: data.GameRules})
+	_ = s.conn.WritePacket(&packet.MovePlayer{
+		EntityRuntimeID: s.clientRID,
+		Position:        data.PlayerPosition,
+		Pitch:           data.Pitch,
+		Yaw:             data.Yaw,
+		HeadYaw:         data.Yaw,
+		Mode:            packet.MoveModeTeleport,
+	})
+}
+
+// samePalette checks if the custom blocks and items of the game data passed are the same, so that a client that
+// received the first can be transferred to a server sending the second.
+func samePalette(a, b minecraft.GameData) bool {
+	return a.UseBlockNetworkIDHashes == b.UseBlockNetworkIDHashes &&
+		slices.EqualFunc(a.CustomBlocks, b.CustomBlocks, func(x, y protocol.BlockEntry) bool {
+			return x.Name == y.Name
+		}) &&
+		slices.EqualFunc(a.Items, b.Items, func(x, y protocol.ItemEntry) bool {
+			return x.Name == y.Name && x.RuntimeID == y.RuntimeID
+		})
+}
+
+// translateEntityIDs translates the entity runtime and unique IDs of the player in a packet between the IDs used by
+// the remote server and the IDs the client was spawned with. Because the two IDs are swapped, the same translation is
+// used for packets in both directions.
+func (s *Session) translateEntityIDs(pk packet.Packet) {
+	s.mu.RLock()
+	rid, uid := s.rid, s.uid
+	s.mu.RUnlock()
+	if rid == s.clientRID && uid == s.clientUID {
+		// The player is still on the server it first joined, so there's nothing to translate.
+		return
+	}
+
+	translateRID := func(id *uint64) {
+		if *id == rid {
+			*id = s.clientRID
+		} else if *id == s.clientRID {
+			*id = rid
+		}
+	}
+	translateUID := func(id *int64) {
+		if *id == uid {
+			*id = s.clientUID
+		} else if *id == s.clientUID {
+			*id = uid
+		}
+	}
+	switch pk := pk.(type) {
+	case *packet.MovePlayer:
+		translateRID(&pk.EntityRuntimeID)
+		translateRID(&pk.RiddenEntityRuntimeID)
+	case *packet.MoveActorAbsolute:
+		translateRID(&pk.EntityRuntimeID)
+	case *packet.MoveActorDelta:
+		translateRID(&pk.EntityRuntimeID)
+	case *packet.SetActorData:
+		translateRID(&pk.EntityRuntimeID)
+	case *packet.SetActorMotion:
+		translateRID(&pk.EntityRuntimeID)
+	case *packet.UpdateAttributes:
+		translateRID(&pk.EntityRuntimeID)
+	case *packet.MobEffect:
+		translateRID(&pk.EntityRuntimeID)
+	case *packet.MobEquipment:
+		translateRID(&pk.EntityRuntimeID)
+	case *packet.MobArmourEquipment:
+		translateRID(&pk.EntityRuntimeID)
+	case *packet.ActorEvent:
+		translateRID(&pk.EntityRuntimeID)
+	case *packet.Animate:
+		translateRID(&pk.EntityRuntimeID)
+	case *packet.Interact:
+		translateRID(&pk.TargetEntityRuntimeID)
+	case *packet.PlayerAction:
+		translateRID(&pk.EntityRuntimeID)
+	case *packet.Respawn:
+		translateRID(&pk.EntityRuntimeID)
+	case *packet.SetLocalPlayerAsInitialised:
+		translateRID(&pk.EntityRuntimeID)
+	case *packet.TakeItemActor:
+		translateRID(&pk.ItemEntityRuntimeID)
+		translateRID(&pk.TakerEntityRuntimeID)
+	case *packet.AddActor:
+		translateRID(&pk.EntityRuntimeID)
+		translateUID(&pk.EntityUniqueID)
+	case *packet.AddPlayer:
+		translateRID(&pk.EntityRuntimeID)
+		translateUID(&pk.AbilityData.EntityUniqueID)
+	case *packet.AddItemActor:
+		translateRID(&pk.EntityRuntimeID)
+		translateUID(&pk.EntityUniqueID)
+	case *packet.RemoveActor:
+		translateUID(&pk.EntityUniqueID)
+	case *packet.SetActorLink:
+		translateUID(&pk.EntityLink.RiddenEntityUniqueID)
+		translateUID(&pk.EntityLink.RiderEntityUniqueID)
+	case *packet.UpdateAbilities:
+		translateUID(&pk.AbilityData.EntityUniqueID)
+	case *packet.AdventureSettings:
+		translateUID(&pk.PlayerUniqueID)
+	case *packet.BossEvent:
+		translateUID(&pk.BossEntityUniqueID)
+		translateUID(&pk.PlayerUniqueID)
+	}
+}